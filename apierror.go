@@ -0,0 +1,178 @@
+package vuitton
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// diagBodyMax is the maximum number of bytes of a response body kept for diagnostics.
+const diagBodyMax = 300
+
+// Kinds of API errors. They are wrapped in an apiError. Use errors.Is to check the kind.
+var (
+	errRateLimited = errors.New("rate limited by the API")
+	errBlocked     = errors.New("blocked by the API")
+	errNotFound    = errors.New("not found")
+	errSchema      = errors.New("unexpected response from the API, it may have changed")
+	errTimeout     = errors.New("request timed out")
+	errUnavailable = errors.New("API unavailable")
+	errUnexpected  = errors.New("request unsuccessful")
+)
+
+// apiError describes an unsuccessful API request, along with the response, if any, for diagnostics.
+type apiError struct {
+	kind   error       // One of the kinds above.
+	status int         // Zero if no response was received.
+	header http.Header // Nil if no response was received.
+	body   string      // Start of the response body.
+	html   bool        // True if the response was an HTML page, such as a captcha, rather than JSON.
+	err    error       // Underlying error, if any.
+}
+
+func (e *apiError) Error() string {
+	msg := e.kind.Error()
+	if e.html {
+		msg += ", got an HTML page instead of JSON, possibly a captcha"
+	}
+	if e.status != 0 {
+		msg += fmt.Sprintf(" (status code %d)", e.status)
+	}
+	if e.err != nil {
+		msg += ": " + e.err.Error()
+	}
+	return msg
+}
+
+// Is reports whether target is the kind of the error, so that errors.Is matches kinds while Unwrap leads to the cause.
+func (e *apiError) Is(target error) bool {
+	return target == e.kind
+}
+
+// Unwrap returns the underlying error, such as a net.Error for a timeout, or a JSON syntax error.
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+// diagnostics returns the response's status code, headers and the start of its body, one per line.
+func (e *apiError) diagnostics() string {
+	lines := make([]string, 0, len(e.header)+2)
+	if e.status != 0 {
+		lines = append(lines, fmt.Sprintf("status code: %d", e.status))
+	}
+	keys := make([]string, 0, len(e.header))
+	for k := range e.header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lines = append(lines, strings.ToLower(k)+": "+strings.Join(e.header[k], ", "))
+	}
+	if e.body != "" {
+		lines = append(lines, "body: "+e.body)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// statusKind returns the kind of error signalled by the given status code.
+func statusKind(status int) error {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return errRateLimited
+	case status == http.StatusForbidden:
+		return errBlocked
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return errNotFound
+	case status >= http.StatusInternalServerError:
+		return errUnavailable
+	default:
+		return errUnexpected
+	}
+}
+
+// checkResponse returns an *apiError if the response is unsuccessful, or is an HTML page rather than JSON, as served
+// when requests are blocked. The response body is only read on error, and must still be closed by the caller.
+func checkResponse(resp *http.Response) error {
+	html := strings.Contains(resp.Header.Get("content-type"), "text/html")
+	if resp.StatusCode == http.StatusOK && !html {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, diagBodyMax+1))
+	e := &apiError{kind: statusKind(resp.StatusCode), status: resp.StatusCode, header: resp.Header, body: snippet(body)}
+	e.html = html || looksLikeHTML(body)
+	if resp.StatusCode == http.StatusOK {
+		e.kind = errBlocked
+	}
+	return e
+}
+
+// requestError converts an error returned by http.Client into an *apiError if the request timed out. Other errors are
+// returned as-is.
+func requestError(err error) error {
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return &apiError{kind: errTimeout, err: err}
+	}
+	return err
+}
+
+// decodeError converts an error from decoding a response body into an *apiError. HTML pages are reported as blocked
+// requests, anything else as a schema mismatch.
+func decodeError(body []byte, err error) error {
+	if looksLikeHTML(body) {
+		return &apiError{kind: errBlocked, html: true, body: snippet(body)}
+	}
+	return &apiError{kind: errSchema, body: snippet(body), err: err}
+}
+
+// looksLikeHTML returns true if the body appears to be an HTML page.
+func looksLikeHTML(body []byte) bool {
+	b := bytes.ToLower(bytes.TrimSpace(body))
+	return bytes.HasPrefix(b, []byte("<!doctype html")) || bytes.HasPrefix(b, []byte("<html"))
+}
+
+// snippet returns the start of the body as a single line, for diagnostics.
+func snippet(body []byte) string {
+	s := string(body)
+	if len(body) > diagBodyMax {
+		s = string(body[:diagBodyMax]) + "..."
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// errorLabel returns a short description of the error, to show in place of a product's stock level.
+func errorLabel(err error) string {
+	switch {
+	case errors.Is(err, errRateLimited):
+		return "Rate limited"
+	case errors.Is(err, errBlocked):
+		return "Blocked"
+	case errors.Is(err, errNotFound):
+		return "Not found"
+	case errors.Is(err, errSchema):
+		return "API changed"
+	case errors.Is(err, errTimeout):
+		return "Timed out"
+	case errors.Is(err, errUnavailable):
+		return "Unavailable"
+	default:
+		return "Error"
+	}
+}
+
+// alertKind returns the kind of the error if it needs the user's attention, ie. if it won't go away by itself, like
+// rate limits, timeouts and outages do. Otherwise, alertKind returns nil.
+func alertKind(err error) error {
+	for _, kind := range []error{errBlocked, errNotFound, errSchema} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package vuitton
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIErrors(t *testing.T) {
+	captcha := "<!DOCTYPE html><html><body>Please verify you are a human" + strings.Repeat(" ", 400) + "</body></html>"
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+		body        string
+		kind        error
+		label       string
+		html        bool
+	}{
+		{"/429", http.StatusTooManyRequests, "application/json", `{"error": "slow down"}`, errRateLimited, "Rate limited", false},
+		{"/403", http.StatusForbidden, "text/html", captcha, errBlocked, "Blocked", true},
+		{"/404", http.StatusNotFound, "application/json", `{}`, errNotFound, "Not found", false},
+		{"/503", http.StatusServiceUnavailable, "text/plain", "down for maintenance", errUnavailable, "Unavailable", false},
+		{"/418", http.StatusTeapot, "text/plain", "", errUnexpected, "Error", false},
+		{"/captcha", http.StatusOK, "text/html; charset=utf-8", captcha, errBlocked, "Blocked", true},
+		{"/captcha-as-json", http.StatusOK, "application/json", captcha, errBlocked, "Blocked", true},
+		{"/schema", http.StatusOK, "application/json", `{"skuAvailability": "none"}`, errSchema, "API changed", false},
+		{"/empty", http.StatusOK, "application/json", `{"skus": []}`, errSchema, "API changed", false},
+	}
+	m := newTestLoop(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, test := range tests {
+			if strings.HasSuffix(r.URL.Path, test.path+"v") {
+				w.Header().Set("content-type", test.contentType)
+				w.Header().Set("x-request-id", "abc123")
+				w.WriteHeader(test.status)
+				_, _ = fmt.Fprint(w, test.body)
+				return
+			}
+		}
+	}))
+
+	for _, test := range tests {
+		// The test path is appended to the product ID, so each test gets its own URL.
+		p := product{URL: "https://en.louisvuitton.com/eng-nl/products/nvprod3130266v", ID: "nvprod" + test.path + "v"}
+		_, err := m.availability(p)
+		if !errors.Is(err, test.kind) {
+			t.Errorf("expected %q for %s, got %v", test.kind, test.path, err)
+			continue
+		}
+		if label := errorLabel(err); label != test.label {
+			t.Errorf("expected label %q for %s, got %q", test.label, test.path, label)
+		}
+		var ae *apiError
+		if !errors.As(err, &ae) {
+			t.Errorf("expected *apiError for %s, got %T", test.path, err)
+			continue
+		}
+		if ae.html != test.html {
+			t.Errorf("expected html %v for %s, got %v", test.html, test.path, ae.html)
+		}
+		if len(ae.body) > diagBodyMax+3 {
+			t.Errorf("expected body to be truncated for %s, got %d bytes", test.path, len(ae.body))
+		}
+		if test.status != http.StatusOK && !strings.Contains(ae.diagnostics(), "x-request-id: abc123") {
+			t.Errorf("expected headers in diagnostics for %s, got:\n%s", test.path, ae.diagnostics())
+		}
+	}
+}
+
+func TestAPIErrorTimeout(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	m := newTestLoop(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+		}
+	}))
+	m.Client.Timeout = 10 * time.Millisecond
+
+	_, err := m.availability(product{URL: "https://en.louisvuitton.com/eng-nl/products/nvprod3130266v"})
+	if !errors.Is(err, errTimeout) {
+		t.Errorf("expected %q, got %v", errTimeout, err)
+	}
+	if label := errorLabel(err); label != "Timed out" {
+		t.Errorf("expected label %q, got %q", "Timed out", label)
+	}
+	// The cause stays reachable.
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		t.Errorf("expected a net.Error that timed out, got %#v", err)
+	}
+}
+
+func TestAlertKind(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected error
+	}{
+		{nil, nil},
+		{errors.New("connection refused"), nil},
+		{&apiError{kind: errRateLimited, status: 429}, nil},
+		{&apiError{kind: errTimeout}, nil},
+		{&apiError{kind: errUnavailable, status: 502}, nil},
+		{&apiError{kind: errBlocked, status: 403}, errBlocked},
+		{&apiError{kind: errNotFound, status: 404}, errNotFound},
+		{&apiError{kind: errSchema}, errSchema},
+	}
+	for _, test := range tests {
+		if actual := alertKind(test.err); actual != test.expected {
+			t.Errorf("expected %v for %v, got %v", test.expected, test.err, actual)
+		}
+	}
+}
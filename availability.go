@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
-// lvURL is the URL that provides product availability on Louis Vuitton's API. Needs a country code and a product ID.
-const lvURL = "https://api.louisvuitton.com/api/%s/catalog/availability/%s"
+// lvURL is the path template of the endpoint that provides product availability on Louis Vuitton's API.
+const lvURL = "/api/{locale}/catalog/availability/{id}"
 
 // avail describes the product availability for a single product, identified by its SKUID.
 type avail struct {
@@ -25,74 +27,197 @@ type response struct {
 
 // availability checks product availability for the given product ID.
 func (m *MainLoop) availability(p product) (inStock bool, err error) {
-	c := m.Client
-	c.Timeout = m.RequestTimeout
+	skus, err := m.skuAvailability(p)
+	if err != nil {
+		return false, err
+	}
+	return selectSKU(skus, p.SKU()), nil
+}
 
+// selectSKU returns the availability of the SKU mySKU. If mySKU is empty, or there is only a single SKU, the
+// availability of the first SKU is returned. selectSKU returns false if skus is empty, or mySKU isn't listed.
+func selectSKU(skus []avail, mySKU string) bool {
+	switch {
+	case len(skus) == 0:
+		return false
+	case len(skus) == 1 || mySKU == "":
+		return skus[0].InStock
+	default:
+		for _, sku := range skus {
+			if sku.SKUID == mySKU {
+				return sku.InStock
+			}
+		}
+		return false
+	}
+}
+
+// skuAvailability returns the availability of every SKU of the given product.
+// skuAvailability returns an error if the product has no SKU's.
+func (m *MainLoop) skuAvailability(p product) ([]avail, error) {
 	pID := p.productID()
 	if pID == "" {
-		return false, errors.New("invalid URL or no product ID")
+		return nil, errors.New("invalid URL or no product ID")
 	}
 
-	url := fmt.Sprintf(lvURL, m.Country.Code(), pID)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	bytes, err := m.fetch(p, pID)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	setCommonHeaders(req)
-	req.Header.Add("origin", p.Domain())
-	req.Header.Add("referer", p.URL)
-
-	resp, err := c.Do(req)
+	var skus response
+	err = json.Unmarshal(bytes, &skus)
 	if err != nil {
-		return false, err
+		return nil, decodeError(bytes, err)
+	}
+	if len(skus.SKUAvailability) == 0 {
+		return nil, &apiError{kind: errSchema, body: snippet(bytes), err: errors.New("no SKU's available")}
 	}
+	return skus.SKUAvailability, nil
+}
 
-	if resp.Body == nil {
-		return false, errors.New("empty response")
+// country returns the country to check the given product in. This is the product's own country if it has one,
+// otherwise the country inferred from the locale in its URL, eg. "jpn-jp" for Japan. If neither is known, the
+// country is MainLoop.Country.
+func (m *MainLoop) country(p product) Country {
+	if p.Country != "" {
+		return p.Country
+	}
+	locale := p.locale()
+	if locale == "" || locale == m.Country.Code() {
+		return m.Country
+	}
+	if c, ok := localeCountry(locale); ok {
+		return c
+	}
+	return m.Country
+}
+
+// fetch returns the availability response body for the given product ID.
+// Responses are cached for cacheTTL, so products sharing a product ID only cost one request. Once a cached response is
+// stale, it is revalidated with a conditional request, which costs a request but no response body if nothing changed.
+// Responses are timestamped when their request is sent, so that the product's own next check finds them stale.
+func (m *MainLoop) fetch(p product, pID string) ([]byte, error) {
+	key := cacheKey(m.country(p).Code(), pID)
+	ttl := m.cacheTTL(p)
+	if ttl > 0 {
+		release := m.cache.acquire(key)
+		defer release()
+	}
+	sent := m.now()
+	cached, fresh, ok := m.cache.get(key, ttl, sent)
+	if ttl > 0 && fresh {
+		return cached.body, nil
+	}
+
+	header := http.Header{}
+	if ttl > 0 && ok {
+		if cached.etag != "" {
+			header.Add("if-none-match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			header.Add("if-modified-since", cached.lastModified)
+		}
+	}
+	resp, err := m.get(p.URL, m.country(p).Code(), m.apiURL(endpointAvailability, "{locale}", m.country(p).Code(), "{id}", pID), header)
+	if err != nil {
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("request unsuccessful, status code is %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotModified && ttl > 0 && ok {
+		cached.fetchedAt = sent
+		m.cache.put(key, cached)
+		return cached.body, nil
+	}
+	if err := checkResponse(resp); err != nil {
+		return nil, err
 	}
 
 	bytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	var skus response
-	err = json.Unmarshal(bytes, &skus)
-	if err != nil {
-		return false, err
+	if ttl > 0 {
+		m.cache.put(key, cachedResponse{
+			body:         bytes,
+			etag:         resp.Header.Get("etag"),
+			lastModified: resp.Header.Get("last-modified"),
+			fetchedAt:    sent,
+		})
 	}
+	return bytes, nil
+}
 
-	mySKU := p.SKU()
-	switch {
-	case len(skus.SKUAvailability) == 0:
-		return false, errors.New("no SKU's available")
-	case len(skus.SKUAvailability) == 1 || mySKU == "":
-		return skus.SKUAvailability[0].InStock, nil
-	default:
-		for _, sku := range skus.SKUAvailability {
-			if sku.SKUID == mySKU {
-				return sku.InStock, nil
-			}
+// get performs a GET request against the API on behalf of the storefront page given by referer, usually a product URL.
+// The locale is the API locale of the country being checked, which the accept-language header follows.
+// Additional headers may be passed via header, which may be nil. The caller must close the response body.
+// If MainLoop.Cookies is set, the session is bootstrapped by visiting the referer first, and refreshed if the API
+// responds with 403 Forbidden, after which the request is retried once. All of these requests use the same header
+// profile, so that the session's cookies are only ever sent by the browser they were issued to. Cookies set by the API
+// are saved along with the others, see MainLoop.saveCookies.
+func (m *MainLoop) get(referer, locale, url string, header http.Header) (*http.Response, error) {
+	defer m.saveCookies(false)
+	hp := m.headerProfile()
+	if err := m.bootstrap(referer, hp, false); err != nil {
+		return nil, fmt.Errorf("unable to start session: %w", err)
+	}
+	resp, err := m.do(referer, locale, url, header, hp)
+	if err != nil || resp.StatusCode != http.StatusForbidden || m.Cookies == nil {
+		return resp, err
+	}
+	_ = resp.Body.Close()
+	if err := m.bootstrap(referer, hp, true); err != nil {
+		return nil, fmt.Errorf("unable to refresh session: %w", err)
+	}
+	return m.do(referer, locale, url, header, hp)
+}
+
+// do performs a single GET request for get, as the browser described by hp.
+func (m *MainLoop) do(referer, locale, url string, header http.Header, hp HeaderProfile) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	setCommonHeaders(req, hp, locale)
+	req.Header.Add("origin", origin(referer))
+	req.Header.Add("referer", referer)
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
 		}
-		return false, nil
 	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, requestError(err)
+	}
+	if resp.Body == nil {
+		return nil, errors.New("empty response")
+	}
+	return resp, nil
 }
 
-// setCommonHeaders adds common headers to the availability request.
-func setCommonHeaders(req *http.Request) {
-	req.Header.Add("authority", "api.louisvuitton.com")
-	req.Header.Add("sec-ch-ua", `"Chromium";v="96", "Opera";v="82", ";Not A Brand";v="99"`)
-	req.Header.Add("accept", "application/json, text/plain, */*")
-	req.Header.Add("dnt", `1`)
-	req.Header.Add("sec-ch-ua-mobile", "?0")
-	req.Header.Add("user-agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/96.0.4664.45 Safari/537.36 OPR/82.0.4227.23")
-	req.Header.Add("sec-ch-ua-platform", "Linux")
-	req.Header.Add("sec-fetch-site", "same-site")
-	req.Header.Add("sec-fetch-mode", "cors")
-	req.Header.Add("sec-fetch-dest", "empty")
-	req.Header.Add("accept-language", "en-US,en;q=0.9,da;q=0.8")
+// getBody performs a GET request like get, and returns the response body.
+// getBody returns an *apiError if the request was unsuccessful.
+func (m *MainLoop) getBody(referer, locale, url string) ([]byte, error) {
+	resp, err := m.get(referer, locale, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkResponse(resp); err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// origin returns the scheme and host of the given URL, eg. "https://en.louisvuitton.com".
+// If the URL is invalid, an empty string is returned.
+func origin(rawURL string) string {
+	u, err := url.Parse(strings.Split(rawURL, "#")[0])
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
 }
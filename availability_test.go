@@ -0,0 +1,57 @@
+package vuitton
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"vuitton/fakelv"
+)
+
+func TestAPIURL(t *testing.T) {
+	tests := []struct {
+		base     string
+		expected string
+	}{
+		{"", "https://api.louisvuitton.com/api/eng-nl/catalog/availability/nvprod3130266v"},
+		{"http://localhost:8080", "http://localhost:8080/api/eng-nl/catalog/availability/nvprod3130266v"},
+		{"http://localhost:8080/", "http://localhost:8080/api/eng-nl/catalog/availability/nvprod3130266v"},
+	}
+	for _, test := range tests {
+		m := MainLoop{APIBase: test.base}
+		if actual := m.apiURL(endpointAvailability, "{locale}", "eng-nl", "{id}", "nvprod3130266v"); actual != test.expected {
+			t.Errorf("expected %q for %q, got %q", test.expected, test.base, actual)
+		}
+	}
+}
+
+func TestAvailabilityFakeServer(t *testing.T) {
+	fake := fakelv.New()
+	fake.AddProduct("nvprod3130266v", "1A9JN8", "1A9JNC")
+	srv := fake.Start()
+	defer srv.Close()
+
+	m := &MainLoop{Country: "DK", APIBase: srv.URL, Client: &http.Client{Timeout: time.Second}}
+	p := product{URL: "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JNC"}
+
+	tests := []struct {
+		change   func()
+		expected bool
+		err      error
+	}{
+		{func() {}, false, nil},
+		{func() { fake.SetStock("nvprod3130266v", "1A9JN8", true) }, false, nil},
+		{func() { fake.SetStock("nvprod3130266v", "1A9JNC", true) }, true, nil},
+		{func() { fake.RateLimit(1) }, false, errRateLimited},
+		{func() { fake.Malform(1) }, false, errBlocked},
+		{func() {}, true, nil},
+	}
+	for i, test := range tests {
+		test.change()
+		inStock, err := m.availability(p)
+		if inStock != test.expected || !errors.Is(err, test.err) {
+			t.Errorf("expected %v and %v for step %d, got %v and %v", test.expected, test.err, i+1, inStock, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package vuitton
+
+import (
+	"sync"
+	"time"
+)
+
+// cacheMaxAge is how long an unused response is kept around for revalidation before it's evicted.
+const cacheMaxAge = time.Hour
+
+// CacheUntilDue is the MainLoop.CacheTTL that reuses availability responses until shortly before the product is due
+// for its next check, see MainLoop.cacheTTL.
+const CacheUntilDue time.Duration = -1
+
+// untrackedCacheTTL is the CacheUntilDue TTL of products that the loop doesn't monitor, eg. when running a command.
+const untrackedCacheTTL = 5 * time.Second
+
+// cachedResponse is an API response body that can be reused while it's fresh, and revalidated after that.
+type cachedResponse struct {
+	body         []byte
+	etag         string
+	lastModified string
+	fetchedAt    time.Time
+	err          error // Error of a failed request, if the cache holds failures. Body is empty if set.
+}
+
+// responseCache is a short-lived cache of API responses, keyed by API locale and product ID.
+// Products that share a product ID (different SKU's) and countries that share an API locale only cost one request.
+// The zero value is an empty cache, ready for use.
+type responseCache struct {
+	sync.Mutex                           // Protects the field(s) below.
+	entries    map[string]cachedResponse // Key is cacheKey(), value is the most recent response.
+	inFlight   map[string]*sync.Mutex    // Key is cacheKey(), value is held while a request for that key is pending.
+}
+
+// cacheKey returns the cache key for the given API locale and product ID.
+func cacheKey(locale, pID string) string {
+	return locale + "/" + pID
+}
+
+// acquire blocks until no other request for the given key is in flight, and returns a func that releases the key.
+// Holding the key while requesting makes concurrent lookups of the same key wait for, and reuse, the first response.
+func (c *responseCache) acquire(key string) func() {
+	c.Lock()
+	if c.inFlight == nil {
+		c.inFlight = make(map[string]*sync.Mutex)
+	}
+	mu, ok := c.inFlight[key]
+	if !ok {
+		mu = &sync.Mutex{}
+		c.inFlight[key] = mu
+	}
+	c.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// get returns the cached response for the given key, and whether it's still fresh, ie. younger than ttl.
+func (c *responseCache) get(key string, ttl time.Duration, now time.Time) (cachedResponse, bool, bool) {
+	c.Lock()
+	defer c.Unlock()
+	resp, ok := c.entries[key]
+	if !ok {
+		return cachedResponse{}, false, false
+	}
+	return resp, now.Sub(resp.fetchedAt) < ttl, true
+}
+
+// put stores the response for the given key, and evicts responses that haven't been refreshed for a long time.
+func (c *responseCache) put(key string, resp cachedResponse) {
+	c.Lock()
+	defer c.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]cachedResponse)
+	}
+	c.entries[key] = resp
+	for k, r := range c.entries {
+		if resp.fetchedAt.Sub(r.fetchedAt) > cacheMaxAge {
+			delete(c.entries, k)
+			delete(c.inFlight, k)
+		}
+	}
+}
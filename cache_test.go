@@ -0,0 +1,176 @@
+package vuitton
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Now()
+	c := responseCache{}
+	key := cacheKey("eng-nl", "nvprod3130266v")
+
+	if _, _, ok := c.get(key, time.Minute, now); ok {
+		t.Fatal("expected empty cache")
+	}
+
+	c.put(key, cachedResponse{body: []byte("{}"), etag: `"abc"`, fetchedAt: now})
+	tests := []struct {
+		at    time.Time
+		fresh bool
+	}{
+		{now, true},
+		{now.Add(59 * time.Second), true},
+		{now.Add(time.Minute), false},
+		{now.Add(time.Hour), false},
+	}
+	for _, tt := range tests {
+		resp, fresh, ok := c.get(key, time.Minute, tt.at)
+		if !ok || resp.etag != `"abc"` {
+			t.Errorf("%s: expected cached response", tt.at.Sub(now))
+		}
+		if fresh != tt.fresh {
+			t.Errorf("%s: expected fresh %t, got %t", tt.at.Sub(now), tt.fresh, fresh)
+		}
+	}
+
+	// Responses that haven't been refreshed for a long time are evicted.
+	c.put(cacheKey("jpn-jp", "nvprod3130266v"), cachedResponse{fetchedAt: now.Add(2 * cacheMaxAge)})
+	if _, _, ok := c.get(key, time.Minute, now); ok {
+		t.Error("expected stale response to be evicted")
+	}
+}
+
+// availabilityServer serves a fixed availability response with validators, and answers conditional requests with
+// 304 Not Modified. It counts the requests it receives, and records the headers of the most recent one.
+type availabilityServer struct {
+	sync.Mutex
+	requests int
+	header   http.Header
+	delay    time.Duration
+}
+
+func (s *availabilityServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	s.requests++
+	s.header = r.Header.Clone()
+	delay := s.delay
+	s.Unlock()
+
+	time.Sleep(delay)
+	if r.Header.Get("if-none-match") == `"v1"` {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("etag", `"v1"`)
+	w.Header().Set("last-modified", "Tue, 01 Jun 2021 12:00:00 GMT")
+	_, _ = fmt.Fprint(w, `{"skuAvailability": [{"skuId": "1A9JN8", "exists": true, "inStock": true}, {"skuId": "1A9JNC", "exists": true, "inStock": false}]}`)
+}
+
+func (s *availabilityServer) state() (int, http.Header) {
+	s.Lock()
+	defer s.Unlock()
+	return s.requests, s.header
+}
+
+func TestFetchSharedProductID(t *testing.T) {
+	const base = "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v"
+	srv := &availabilityServer{}
+	m := newTestLoop(t, srv)
+	clock := newFakeClock()
+	m.Clock = clock
+	m.CacheTTL = CacheUntilDue
+	m.AvailabilityInterval = 30 * time.Second
+	a, b := product{URL: base + "#1A9JN8"}, product{URL: base + "#1A9JNC"}
+	m.products = map[string]stockLevel{a.key(): {product: a}, b.key(): {product: b}}
+
+	// The TTL is the interval, less half the spacing of 15 seconds.
+	if ttl := m.cacheTTL(a); ttl != 22500*time.Millisecond {
+		t.Errorf("expected TTL of 22.5s, got %s", ttl)
+	}
+	if ttl := m.cacheTTL(product{URL: base}); ttl != untrackedCacheTTL {
+		t.Errorf("expected TTL of %s for product that isn't monitored, got %s", untrackedCacheTTL, ttl)
+	}
+
+	tests := []struct {
+		p        product
+		advance  time.Duration
+		inStock  bool
+		requests int
+	}{
+		{a, 0, true, 1},
+		{b, 15 * time.Second, false, 1},
+		{a, 15 * time.Second, true, 2},
+		{b, 15 * time.Second, false, 2},
+	}
+	for i, test := range tests {
+		clock.Advance(test.advance)
+		inStock, err := m.availability(test.p)
+		if err != nil {
+			t.Fatalf("unexpected error for step %d: %s", i+1, err)
+		}
+		if requests, _ := srv.state(); inStock != test.inStock || requests != test.requests {
+			t.Errorf("expected %v after %d requests for step %d, got %v after %d", test.inStock, test.requests, i+1, inStock, requests)
+		}
+	}
+}
+
+func TestFetchConcurrent(t *testing.T) {
+	const base = "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v"
+	srv := &availabilityServer{delay: 50 * time.Millisecond}
+	m := newTestLoop(t, srv)
+	m.CacheTTL = time.Minute
+
+	var wg sync.WaitGroup
+	for _, sku := range []string{"1A9JN8", "1A9JNC", "1A9JN8", "1A9JNC", "1A9JNE"} {
+		wg.Add(1)
+		go func(p product) {
+			defer wg.Done()
+			if _, err := m.availability(p); err != nil {
+				t.Errorf("unexpected error for %s: %s", p.URL, err)
+			}
+		}(product{URL: base + "#" + sku})
+	}
+	wg.Wait()
+	if requests, _ := srv.state(); requests != 1 {
+		t.Errorf("expected 1 request for concurrent lookups, got %d", requests)
+	}
+}
+
+func TestFetchRevalidate(t *testing.T) {
+	srv := &availabilityServer{}
+	m := newTestLoop(t, srv)
+	clock := newFakeClock()
+	m.Clock = clock
+	m.CacheTTL = time.Minute
+	p := product{URL: "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8"}
+
+	tests := []struct {
+		advance     time.Duration
+		requests    int
+		ifNoneMatch string
+		ifModified  string
+	}{
+		{0, 1, "", ""},
+		{59 * time.Second, 1, "", ""},
+		{time.Second, 2, `"v1"`, "Tue, 01 Jun 2021 12:00:00 GMT"},
+		{30 * time.Second, 2, `"v1"`, "Tue, 01 Jun 2021 12:00:00 GMT"},
+	}
+	for i, test := range tests {
+		clock.Advance(test.advance)
+		inStock, err := m.availability(p)
+		if err != nil || !inStock {
+			t.Fatalf("expected in stock from cached body for step %d, got %v and %v", i+1, inStock, err)
+		}
+		requests, header := srv.state()
+		if requests != test.requests {
+			t.Errorf("expected %d requests for step %d, got %d", test.requests, i+1, requests)
+		}
+		if header.Get("if-none-match") != test.ifNoneMatch || header.Get("if-modified-since") != test.ifModified {
+			t.Errorf("expected %q and %q for step %d, got %q and %q", test.ifNoneMatch, test.ifModified, i+1, header.Get("if-none-match"), header.Get("if-modified-since"))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package vuitton
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ClientConfig configures the HTTP client used for all requests. Zero values select sensible defaults, except for the
+// timeouts, where zero means no timeout.
+type ClientConfig struct {
+	Proxy                 string         // URL of an HTTP, HTTPS or SOCKS5 proxy, eg. "socks5://localhost:1080". Empty uses the environment.
+	ProxyPool             *ProxyPool     // Proxies to spread requests across. Can't be combined with Proxy.
+	Jar                   http.CookieJar // Cookie jar, eg. a *CookieJar. Nil disables cookies.
+	CAFile                string         // PEM file with CA certificates to trust in addition to the system's, eg. for a TLS-intercepting proxy.
+	MaxIdleConnsPerHost   int            // Maximum number of idle connections kept per host.
+	IdleConnTimeout       time.Duration  // How long idle connections are kept.
+	DialTimeout           time.Duration  // Timeout for establishing a TCP connection.
+	TLSHandshakeTimeout   time.Duration  // Timeout for the TLS handshake.
+	ResponseHeaderTimeout time.Duration  // Timeout for receiving response headers once the request has been sent.
+	Timeout               time.Duration  // Timeout for the whole request, including reading the response body.
+	Record                string         // Directory to record every exchange to, for later replay.
+	Replay                string         // Directory to replay recorded exchanges from, instead of using the network.
+}
+
+// defaultMaxIdleConnsPerHost is used when ClientConfig.MaxIdleConnsPerHost is zero. Nearly all requests go to the API
+// host, so this is a bit more than http.DefaultMaxIdleConnsPerHost.
+const defaultMaxIdleConnsPerHost = 4
+
+// proxySchemes are the proxy URL schemes supported by http.Transport.
+var proxySchemes = map[string]bool{"http": true, "https": true, "socks5": true, "socks5h": true}
+
+// NewClient builds an HTTP client from the given configuration. The client is safe for concurrent use, and must not be
+// modified once in use.
+func NewClient(cfg ClientConfig) (*http.Client, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if cfg.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	t.DialContext = (&net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	t.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+
+	if cfg.Proxy != "" && cfg.ProxyPool != nil {
+		return nil, errors.New("a proxy and a proxy pool can't be used together")
+	}
+	if cfg.Record != "" && cfg.Replay != "" {
+		return nil, errors.New("recording and replaying can't be used together")
+	}
+	if cfg.Replay != "" {
+		if info, err := os.Stat(cfg.Replay); err != nil || !info.IsDir() {
+			return nil, errors.New("no directory to replay from at " + cfg.Replay)
+		}
+		return &http.Client{Transport: replayTransport{dir: cfg.Replay}, Jar: cfg.Jar, Timeout: cfg.Timeout}, nil
+	}
+	if cfg.Proxy != "" {
+		u, err := parseProxyURL(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + cfg.CAFile)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	var rt http.RoundTripper = t
+	if cfg.ProxyPool != nil {
+		t.Proxy = cfg.ProxyPool.proxy
+		rt = poolTransport{pool: cfg.ProxyPool, next: t}
+	}
+	if cfg.Record != "" {
+		if err := os.MkdirAll(cfg.Record, 0755); err != nil {
+			return nil, err
+		}
+		rt = recordTransport{dir: cfg.Record, next: rt}
+	}
+	return &http.Client{Transport: rt, Jar: cfg.Jar, Timeout: cfg.Timeout}, nil
+}
@@ -0,0 +1,88 @@
+package vuitton
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewClientProxy(t *testing.T) {
+	// A plain HTTP request through a proxy carries the absolute URL, so the stand-in can tell it was proxied.
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = fmt.Fprint(w, "ok")
+	}))
+	defer proxy.Close()
+
+	c, err := NewClient(ClientConfig{Proxy: proxy.URL, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get("http://api.louisvuitton.com/api/eng-nl/catalog/availability/nvprod3130266v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if proxied != "http://api.louisvuitton.com/api/eng-nl/catalog/availability/nvprod3130266v" {
+		t.Errorf("expected request to go through proxy, proxy got %q", proxied)
+	}
+}
+
+func TestNewClientCAFile(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "ok")
+	}))
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0) // The failing handshake below is expected.
+	srv.StartTLS()
+	defer srv.Close()
+
+	// Without the server's certificate, the request must fail.
+	c, err := NewClient(ClientConfig{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := c.Get(srv.URL); err == nil {
+		_ = resp.Body.Close()
+		t.Error("expected certificate error")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err = NewClient(ClientConfig{CAFile: caFile, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestNewClientInvalid(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := ioutil.WriteFile(empty, []byte("no certificates here"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []ClientConfig{
+		{Proxy: "ftp://proxy.example.com"},
+		{Proxy: "not a url"},
+		{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		{CAFile: empty},
+	}
+	for _, test := range tests {
+		if _, err := NewClient(test); err == nil {
+			t.Errorf("expected error for %+v", test)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package vuitton
+
+import "time"
+
+// Clock tells the time and creates the tickers and timers that drive MainLoop. If MainLoop.Clock is nil, the system
+// clock is used. Tests substitute a clock they can advance.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+	NewTimer(d time.Duration) Timer
+}
+
+// Ticker is the part of time.Ticker that MainLoop uses.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Timer is the part of time.Timer that MainLoop uses.
+type Timer interface {
+	C() <-chan time.Time
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+// View displays the state of MainLoop. cursor.Area implements it, tests substitute a view that records its content.
+type View interface {
+	Clear()
+	Update(content string)
+}
+
+// systemClock is the Clock that uses package time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// systemTicker wraps time.Ticker, whose channel is a field rather than a method.
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+// systemTimer wraps time.Timer, whose channel is a field rather than a method.
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// clock returns MainLoop.Clock, or the system clock if it isn't set.
+func (m *MainLoop) clock() Clock {
+	if m.Clock == nil {
+		return systemClock{}
+	}
+	return m.Clock
+}
+
+// now returns the current time according to MainLoop.Clock.
+func (m *MainLoop) now() time.Time {
+	return m.clock().Now()
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 	"vuitton"
+	"vuitton/fakelv"
 
 	"github.com/atomicgo/cursor"
 	"github.com/gen2brain/beeep"
@@ -22,8 +24,41 @@ var (
 	notify               bool
 	pFileInterval        time.Duration
 	availabilityInterval time.Duration
+	requestBudget        int
+	cacheTTL             time.Duration
+	priceChange          float64
+	hotInterval          time.Duration
+	hotDuration          time.Duration
+	watches              stringList
+	watchInterval        time.Duration
+	autoAdd              bool
+	stores               string
+	clientConfig         vuitton.ClientConfig
+	proxyFile            string
+	proxySelect          string
+	proxyFailures        int
+	proxyBench           time.Duration
+	headersFile          string
+	headerProfile        string
+	cookieFile           string
+	apiBase              string
+	endpointsFile        string
+	endpointFlags        stringList
+	apiEndpoints         map[string]string
 )
 
+// stringList is a flag that may be given several times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
 // init handles CLI flags.
 func init() {
 	flag.StringVar(&countryCode, "country", "dk", "country code to check availability for, two letters, any case")
@@ -32,10 +67,54 @@ func init() {
 	flag.BoolVar(&notify, "notify", true, "attempt to notify via desktop notification when product comes in stock")
 	flag.DurationVar(&pFileInterval, "pfilecheck", 10*time.Second, "interval between reloads of 'p-file' (product-file)")
 	flag.DurationVar(&availabilityInterval, "availabilitycheck", 30*time.Second, "interval between product availability checks")
+	flag.DurationVar(&hotInterval, "hotcheck", 10*time.Second, "interval between availability checks for products recently seen in stock, 0 disables")
+	flag.DurationVar(&hotDuration, "hotduration", 10*time.Minute, "how long a product is checked more frequently after it was last seen in stock")
+	flag.Float64Var(&priceChange, "pricechange", 5, "notify when a product's price changes by at least this many percent, 0 disables")
+	flag.Var(&watches, "watch", "category URL or search query to watch for new products, may be given several times")
+	flag.DurationVar(&watchInterval, "watchcheck", 5*time.Minute, "interval between checks for new products in watched categories and searches")
+	flag.BoolVar(&autoAdd, "autoadd", false, "add new products found in watched categories and searches to the p-file")
+	flag.StringVar(&stores, "stores", "", "comma-separated ID's of boutiques to check availability in, see the stores command")
+	flag.IntVar(&requestBudget, "budget", 0, "maximum number of availability requests per minute, 0 means no limit")
+	flag.DurationVar(&cacheTTL, "cachettl", 0, "how long availability responses are reused for products sharing a product ID, 0 means until the product is due again, negative disables")
+	flag.StringVar(&clientConfig.Proxy, "proxy", "", "URL of an HTTP, HTTPS or SOCKS5 proxy to send requests through, eg. socks5://localhost:1080, defaults to HTTPS_PROXY")
+	flag.StringVar(&proxyFile, "proxies", "", "name of file to load proxy URLs from, one per line, requests are spread across them")
+	flag.StringVar(&proxySelect, "proxyselect", vuitton.RoundRobin, "how proxies are selected from the proxy file, roundrobin or lru")
+	flag.IntVar(&proxyFailures, "proxyfailures", 3, "number of failed requests in a row before a proxy is benched")
+	flag.DurationVar(&proxyBench, "proxybench", 5*time.Minute, "how long a failing proxy is benched")
+	flag.StringVar(&clientConfig.Record, "record", "", "directory to record all HTTP responses to, for use with -replay")
+	flag.StringVar(&clientConfig.Replay, "replay", "", "directory to replay HTTP responses from, as recorded with -record, instead of using the network")
+	flag.StringVar(&clientConfig.CAFile, "cacert", "", "PEM file with CA certificates to trust in addition to the system's")
+	flag.IntVar(&clientConfig.MaxIdleConnsPerHost, "maxconns", 4, "maximum number of idle connections kept per host")
+	flag.DurationVar(&clientConfig.IdleConnTimeout, "idletimeout", 90*time.Second, "how long idle connections are kept")
+	flag.DurationVar(&clientConfig.DialTimeout, "dialtimeout", 5*time.Second, "timeout for connecting to a host, 0 disables")
+	flag.DurationVar(&clientConfig.TLSHandshakeTimeout, "tlstimeout", 5*time.Second, "timeout for TLS handshakes, 0 disables")
+	flag.DurationVar(&clientConfig.ResponseHeaderTimeout, "headertimeout", 5*time.Second, "timeout for receiving response headers, 0 disables")
+	flag.DurationVar(&clientConfig.Timeout, "timeout", 10*time.Second, "timeout for a whole request, including the response body, 0 disables")
+	flag.StringVar(&headersFile, "headers", "", "name of JSON file to load request header profiles from, defaults to built-in profiles")
+	flag.StringVar(&headerProfile, "headerprofile", "", "name of the header profile to send requests with, or 'rotate' to rotate through all profiles, defaults to the first profile")
+	flag.StringVar(&cookieFile, "cookies", "", "name of file to keep storefront cookies in, enables visiting the storefront before calling the API")
+	flag.StringVar(&apiBase, "apibase", "", "scheme and host of the API, eg. http://localhost:8080 for the fake server, defaults to Louis Vuitton's API")
+	flag.StringVar(&endpointsFile, "endpoints", "", "name of JSON file to load API endpoint templates from, eg. {\"availability\": \"/api/{locale}/catalog/availability/{id}\"}")
+	flag.Var(&endpointFlags, "endpoint", "API endpoint template as name=template, overrides the endpoints file, may be given several times")
+	flag.Usage = usage
 	flag.Parse()
 
 }
 
+// usage prints the available commands and flags.
+func usage() {
+	out := flag.CommandLine.Output()
+	_, _ = fmt.Fprintf(out, "Usage: %s [flags] [command]\n\n", os.Args[0])
+	_, _ = fmt.Fprintln(out, "Without a command, the product file is monitored until interrupted. Commands:")
+	_, _ = fmt.Fprintln(out, "  skus <url>\tlist the SKU's of a product, along with their size and colour")
+	_, _ = fmt.Fprintln(out, "  compare <url> [country ...]\tcompare availability and price across countries, all countries by default")
+	_, _ = fmt.Fprintln(out, "  search [-add 1,2] <query>\tsearch the catalog, optionally adding results by number to the product file")
+	_, _ = fmt.Fprintln(out, "  stores\tlist the boutiques in the country, along with their ID's")
+	_, _ = fmt.Fprintln(out, "  fakeserver [-addr localhost:8080] [product[#sku,...] ...]\tserve a fake API with the given products, for use with -apibase")
+	_, _ = fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
 // desktopNotification attempts to push a desktop notification when a product comes in stock.
 func desktopNotification(title, msg string) {
 	if notify {
@@ -68,6 +147,59 @@ func main() {
 		msg := fmt.Sprintf("Invalid duration for p-file interval, must be at least %d seconds\n", minIntervalsSeconds)
 		printErrorUsageAndExit(3, msg)
 	}
+	if hotInterval != 0 && hotInterval.Seconds() < minIntervalsSeconds {
+		msg := fmt.Sprintf("Invalid duration for hot interval, must be 0 or at least %d seconds\n", minIntervalsSeconds)
+		printErrorUsageAndExit(3, msg)
+	}
+	if watchInterval.Seconds() < minIntervalsSeconds {
+		msg := fmt.Sprintf("Invalid duration for watch interval, must be at least %d seconds\n", minIntervalsSeconds)
+		printErrorUsageAndExit(3, msg)
+	}
+	if priceChange < 0 {
+		printErrorUsageAndExit(3, "Invalid price change, must be zero or a positive percentage\n")
+	}
+	if requestBudget < 0 {
+		printErrorUsageAndExit(3, "Invalid request budget, must be zero or more requests per minute\n")
+	}
+
+	// Load API endpoints.
+	templates, err := endpointTemplates()
+	if err != nil {
+		printErrorUsageAndExit(8, fmt.Sprintf("Invalid API endpoints: %s\n", err.Error()))
+	}
+	apiEndpoints = templates
+
+	// Build the HTTP client, which is shared by all requests.
+	if proxyFile != "" {
+		pool, err := vuitton.LoadProxyPool(proxyFile, proxySelect, proxyFailures, proxyBench)
+		if err != nil {
+			printErrorUsageAndExit(8, fmt.Sprintf("Unable to load proxies: %s\n", err.Error()))
+		}
+		clientConfig.ProxyPool = pool
+	}
+	var jar *vuitton.CookieJar
+	if cookieFile != "" {
+		var err error
+		if jar, err = vuitton.NewCookieJar(cookieFile); err != nil {
+			printErrorUsageAndExit(8, fmt.Sprintf("Unable to load cookies: %s\n", err.Error()))
+		}
+		clientConfig.Jar = jar
+	}
+	client, err := vuitton.NewClient(clientConfig)
+	if err != nil {
+		printErrorUsageAndExit(8, fmt.Sprintf("Invalid HTTP client options: %s\n", err.Error()))
+	}
+
+	// Load header profiles.
+	profiles, err := headerProfiles()
+	if err != nil {
+		printErrorUsageAndExit(8, fmt.Sprintf("Invalid header profiles: %s\n", err.Error()))
+	}
+
+	// Run command, if any.
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(country, client, jar, profiles, flag.Args()))
+	}
 
 	// Check if p-file exists.
 	info, err := os.Stat(pFileName)
@@ -85,12 +217,25 @@ func main() {
 		ViewPort:             &view,
 		Country:              country,
 		AvailabilityInterval: availabilityInterval,
-		RequestTimeout:       5 * time.Second,
-		Client:               &http.Client{},
+		RequestBudget:        requestBudget,
+		HotInterval:          hotInterval,
+		HotDuration:          hotDuration,
+		PriceChange:          priceChange,
+		CacheTTL:             loopCacheTTL(),
+		Client:               client,
 		PFileName:            pFileName,
 		OpenBrowser:          openBrowser,
 		Notification:         desktopNotification,
 		PFileInterval:        pFileInterval,
+		APIBase:              apiBase,
+		Endpoints:            apiEndpoints,
+		Watches:              watches,
+		WatchInterval:        watchInterval,
+		AutoAdd:              autoAdd,
+		Stores:               storeIDs(),
+		Proxies:              clientConfig.ProxyPool,
+		HeaderProfiles:       profiles,
+		Cookies:              jar,
 	}
 	err = m.Run()
 	if err != nil {
@@ -100,6 +245,155 @@ func main() {
 	os.Exit(exitCode)
 }
 
+// runCommand runs the command given by args, and returns the exit code.
+func runCommand(country vuitton.Country, client *http.Client, jar *vuitton.CookieJar, profiles []vuitton.HeaderProfile, args []string) int {
+	m := vuitton.MainLoop{
+		Country:        country,
+		Client:         client,
+		Cookies:        jar,
+		HeaderProfiles: profiles,
+		CacheTTL:       loopCacheTTL(),
+		PFileName:      pFileName,
+		APIBase:        apiBase,
+		Endpoints:      apiEndpoints,
+	}
+
+	var err error
+	switch args[0] {
+	case "skus":
+		if len(args) != 2 {
+			printErrorUsageAndExit(7, "The skus command needs exactly one product URL\n")
+		}
+		err = m.PrintSKUs(os.Stdout, args[1])
+	case "compare":
+		if len(args) < 2 {
+			printErrorUsageAndExit(7, "The compare command needs a product URL, optionally followed by country codes\n")
+		}
+		countries := make([]vuitton.Country, 0, len(args)-2)
+		for _, c := range args[2:] {
+			countries = append(countries, vuitton.Country(c))
+		}
+		err = m.Compare(os.Stdout, args[1], countries)
+	case "search":
+		fs := flag.NewFlagSet("search", flag.ExitOnError)
+		add := fs.String("add", "", "comma-separated result numbers to add to the product file, eg. 1,3")
+		_ = fs.Parse(args[1:])
+		if fs.NArg() == 0 {
+			printErrorUsageAndExit(7, "The search command needs a query\n")
+		}
+		var nums []int
+		for _, s := range strings.Split(*add, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			n, convErr := strconv.Atoi(s)
+			if convErr != nil {
+				printErrorUsageAndExit(7, fmt.Sprintf("Invalid result number %q\n", s))
+			}
+			nums = append(nums, n)
+		}
+		err = m.Search(os.Stdout, strings.Join(fs.Args(), " "), nums)
+	case "stores":
+		err = m.PrintStores(os.Stdout)
+	case "fakeserver":
+		err = runFakeServer(args[1:])
+	default:
+		printErrorUsageAndExit(7, fmt.Sprintf("Unknown command %q\n", args[0]))
+	}
+	if err != nil {
+		fmt.Println("Error:", err.Error())
+		return 6
+	}
+	return 0
+}
+
+// runFakeServer serves a fake API with the products given by args, until interrupted.
+func runFakeServer(args []string) error {
+	fs := flag.NewFlagSet("fakeserver", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	_ = fs.Parse(args)
+
+	srv := fakelv.New()
+	for _, arg := range fs.Args() {
+		pID, skus := arg, ""
+		if i := strings.IndexByte(arg, '#'); i >= 0 {
+			pID, skus = arg[:i], arg[i+1:]
+		}
+		if skus == "" {
+			srv.AddProduct(pID)
+			continue
+		}
+		srv.AddProduct(pID, strings.Split(skus, ",")...)
+	}
+	fmt.Printf("Serving fake API on http://%s, run the monitor with -apibase http://%s\n", *addr, *addr)
+	fmt.Printf("Toggle stock with: curl -X POST 'http://%s/control/stock?product=<id>&sku=<sku>&instock=true'\n", *addr)
+	return http.ListenAndServe(*addr, srv)
+}
+
+// headerProfiles returns the header profiles selected by the headers and headerprofile flags.
+func headerProfiles() ([]vuitton.HeaderProfile, error) {
+	profiles := vuitton.DefaultHeaderProfiles
+	if headersFile != "" {
+		var err error
+		if profiles, err = vuitton.LoadHeaderProfiles(headersFile); err != nil {
+			return nil, err
+		}
+	}
+	switch headerProfile {
+	case "":
+		return profiles[:1], nil
+	case "rotate":
+		return profiles, nil
+	}
+	hp, err := vuitton.SelectHeaderProfile(profiles, headerProfile)
+	if err != nil {
+		return nil, err
+	}
+	return []vuitton.HeaderProfile{hp}, nil
+}
+
+// endpointTemplates returns the API endpoint templates given by the endpoints and endpoint flags, after validating
+// them and the apibase flag.
+func endpointTemplates() (map[string]string, error) {
+	templates := make(map[string]string)
+	if endpointsFile != "" {
+		var err error
+		if templates, err = vuitton.LoadEndpoints(endpointsFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range endpointFlags {
+		name, tmpl := f, ""
+		if i := strings.Index(f, "="); i >= 0 {
+			name, tmpl = f[:i], f[i+1:]
+		}
+		templates[name] = tmpl
+	}
+	return templates, vuitton.ValidateEndpoints(apiBase, templates)
+}
+
+// loopCacheTTL returns the MainLoop.CacheTTL given by the cachettl flag, where zero selects CacheUntilDue.
+func loopCacheTTL() time.Duration {
+	switch {
+	case cacheTTL == 0:
+		return vuitton.CacheUntilDue
+	case cacheTTL < 0:
+		return 0
+	}
+	return cacheTTL
+}
+
+// storeIDs returns the store ID's given by the stores flag.
+func storeIDs() []string {
+	ids := make([]string, 0)
+	for _, id := range strings.Split(stores, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
 func printErrorUsageAndExit(exitCode int, msg string) {
 	fmt.Println("Error:", msg)
 	flag.Usage()
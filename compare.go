@@ -0,0 +1,126 @@
+package vuitton
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// comparison is the availability and price of a product in a single country.
+type comparison struct {
+	country Country
+	inStock map[string]bool // Key is SKU, value is availability. SKU's missing from the map aren't sold in the country.
+	meta    metadata
+	err     error
+}
+
+// Compare checks availability and price of the product identified by rawURL in each of the given countries, and writes
+// the results as a table to w. If no countries are given, all supported countries are compared. If the URL lists
+// SKU's, only those SKU's are compared.
+// Countries that share an API locale share responses via the cache, so they only cost one request if CacheTTL is set.
+func (m *MainLoop) Compare(w io.Writer, rawURL string, countries []Country) error {
+	p := product{URL: rawURL}
+	if !p.Valid() || p.productID() == "" {
+		return errors.New("invalid product URL or no product ID")
+	}
+	if len(countries) == 0 {
+		countries = supportedCountries()
+	}
+	cs := make([]Country, 0, len(countries))
+	for _, c := range countries {
+		if !c.Valid() {
+			return fmt.Errorf("invalid country %q", string(c))
+		}
+		cs = append(cs, Country(strings.ToUpper(string(c))))
+	}
+
+	skus := p.SKUs()
+	listed := len(skus) > 0
+	sizes := make(map[string]string) // Key is SKU, value is size label.
+	cmps := make([]comparison, 0, len(cs))
+	for _, c := range cs {
+		cp := p
+		cp.Country = c
+		cmp := comparison{country: c, inStock: make(map[string]bool)}
+		avails, err := m.skuAvailability(cp)
+		if err != nil {
+			cmp.err = err
+			cmps = append(cmps, cmp)
+			continue
+		}
+		for _, a := range avails {
+			cmp.inStock[a.SKUID] = a.InStock
+		}
+		if !listed {
+			skus = appendMissing(skus, avails)
+		}
+		if details, err := m.details(cp); err == nil {
+			cmp.meta = details.metadata(p.SKU())
+			for _, sku := range details.SKUs {
+				sizes[sku.SKU] = sku.Size
+			}
+		}
+		cmps = append(cmps, cmp)
+	}
+
+	renderComparison(w, cmps, skus, sizes)
+	return nil
+}
+
+// appendMissing appends the SKU's from avails that aren't already in skus.
+func appendMissing(skus []string, avails []avail) []string {
+	for _, a := range avails {
+		found := false
+		for _, sku := range skus {
+			if sku == a.SKUID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			skus = append(skus, a.SKUID)
+		}
+	}
+	return skus
+}
+
+// renderComparison writes a table with a row per country, and a column per SKU, followed by any errors.
+func renderComparison(w io.Writer, cmps []comparison, skus []string, sizes map[string]string) {
+	header := []string{"Country", "Region", "Price"}
+	for _, sku := range skus {
+		if size := sizes[sku]; size != "" {
+			sku = fmt.Sprintf("%s (%s)", sku, size)
+		}
+		header = append(header, sku)
+	}
+
+	t := tablewriter.NewWriter(w)
+	t.SetHeader(header)
+	var errs []string
+	for _, cmp := range cmps {
+		row := []string{string(cmp.country), cmp.country.Code(), cmp.meta.formatPrice()}
+		for _, sku := range skus {
+			inStock, ok := cmp.inStock[sku]
+			switch {
+			case cmp.err != nil || !ok:
+				row = append(row, "-")
+			case inStock:
+				row = append(row, "Yes")
+			default:
+				row = append(row, "No")
+			}
+		}
+		t.Append(row)
+		if cmp.err != nil {
+			errs = append(errs, fmt.Sprintf("Unable to check availability in %s: %s", cmp.country, cmp.err.Error()))
+		}
+	}
+	t.Render()
+
+	for _, e := range errs {
+		_, _ = fmt.Fprintln(w, e)
+	}
+}
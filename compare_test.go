@@ -0,0 +1,50 @@
+package vuitton
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRenderComparison(t *testing.T) {
+	cmps := []comparison{
+		{country: "DK", inStock: map[string]bool{"1A9JN8": true, "1A9JNC": false}, meta: metadata{Price: 1090, Currency: "EUR"}},
+		{country: "JP", inStock: map[string]bool{"1A9JN8": false}, meta: metadata{Price: 165000, Currency: "JPY"}},
+		{country: "US", inStock: map[string]bool{}, err: errors.New("request unsuccessful, status code is 404")},
+	}
+	b := strings.Builder{}
+	renderComparison(&b, cmps, []string{"1A9JN8", "1A9JNC"}, map[string]string{"1A9JN8": "8"})
+	out := b.String()
+
+	expected := []string{
+		"1A9JN8 (8)",
+		"| DK      | eng-nl | 1090.00 EUR   | Yes        | No     |",
+		"| JP      | jpn-jp | 165000.00 JPY | No         | -      |",
+		"| US      | eng-us |               | -          | -      |",
+		"Unable to check availability in US: request unsuccessful, status code is 404",
+	}
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, out)
+		}
+	}
+}
+
+func TestCompareCountries(t *testing.T) {
+	m := newTestLoop(t, http.NotFoundHandler())
+	countries := []Country{"dk", "jp"}
+	var buf bytes.Buffer
+	if err := m.Compare(&buf, "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v", countries); err != nil {
+		t.Fatal(err)
+	}
+	// Countries are upper-cased for the comparison, without changing the caller's slice.
+	if out := buf.String(); !strings.Contains(out, "DK") || !strings.Contains(out, "JP") {
+		t.Errorf("expected DK and JP in output, got:\n%s", out)
+	}
+	if fmt.Sprint(countries) != "[dk jp]" {
+		t.Errorf("expected countries [dk jp], got %v", countries)
+	}
+}
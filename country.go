@@ -1,6 +1,9 @@
 package vuitton
 
-import "strings"
+import (
+	"sort"
+	"strings"
+)
 
 // countryMap acts as an allow-list and a conversion chart.
 var countryMap = map[string]string{
@@ -58,3 +61,31 @@ func (c Country) Code() string {
 	}
 	return ""
 }
+
+// supportedCountries returns all supported countries, sorted by country code.
+func supportedCountries() []Country {
+	cs := make([]Country, 0, len(countryMap))
+	for c := range countryMap {
+		cs = append(cs, Country(c))
+	}
+	sort.Slice(cs, func(i, j int) bool { return cs[i] < cs[j] })
+	return cs
+}
+
+// localeCountry returns the country that corresponds to the given API locale, eg. "jpn-jp" returns "JP".
+// Several countries share some locales; in that case, the country named by the locale is preferred, eg. "eng-nl"
+// returns "NL", otherwise the first one alphabetically. localeCountry returns false if no country uses the locale.
+func localeCountry(locale string) (Country, bool) {
+	locale = strings.ToLower(locale)
+	if parts := strings.Split(locale, "-"); len(parts) == 2 {
+		if c := Country(strings.ToUpper(parts[1])); c.Code() == locale {
+			return c, true
+		}
+	}
+	for _, c := range supportedCountries() {
+		if c.Code() == locale {
+			return c, true
+		}
+	}
+	return "", false
+}
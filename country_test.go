@@ -25,3 +25,27 @@ func TestCountryValid(t *testing.T) {
 		}
 	}
 }
+
+func TestLocaleCountry(t *testing.T) {
+	tests := []struct {
+		in  string
+		out Country
+		ok  bool
+	}{
+		{"", "", false},
+		{"xxx-xx", "", false},
+		{"jpn-jp", "JP", true},
+		{"JPN-JP", "JP", true},
+		{"eng-nl", "NL", true},
+		{"eng-gb", "UK", true},
+		{"eng-ae", "KW", true},
+		{"ara-ae", "AE", true},
+	}
+
+	for _, tt := range tests {
+		actual, ok := localeCountry(tt.in)
+		if actual != tt.out || ok != tt.ok {
+			t.Errorf("%s: expected %q and %t, got %q and %t", tt.in, tt.out, tt.ok, actual, ok)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package vuitton
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// lvProductURL is the path template of the endpoint that provides product details on Louis Vuitton's API.
+const lvProductURL = "/api/{locale}/catalog/product/{id}"
+
+// detailsTTL is how long product details are cached. Details rarely change, so they're cached for a long time.
+const detailsTTL = 30 * time.Minute
+
+// detailsRetry is how long a failure to fetch product details is cached. Details are fetched along with every
+// availability check, but are not counted against the request budget, so failures must not be retried on every check.
+const detailsRetry = 5 * time.Minute
+
+// price is a price as found in the product details. The API sometimes provides prices as strings, with thousands
+// separators, and sometimes as plain numbers; price accepts both.
+type price float64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *price) UnmarshalJSON(bytes []byte) error {
+	s := strings.Trim(string(bytes), `"`)
+	if s == "" || s == "null" {
+		*p = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+	if err != nil {
+		return fmt.Errorf("invalid price %s", bytes)
+	}
+	*p = price(f)
+	return nil
+}
+
+// offer describes the price of a single SKU.
+type offer struct {
+	Price    price  `json:"price"`
+	Currency string `json:"priceCurrency"`
+}
+
+// image describes a single product image.
+type image struct {
+	URL string `json:"contentUrl"`
+}
+
+// skuDetails describes a single variant of a product, identified by its SKU.
+type skuDetails struct {
+	SKU    string  `json:"identifier"`
+	Name   string  `json:"name"`
+	Color  string  `json:"color"`
+	Size   string  `json:"size"`
+	Offers offer   `json:"offers"`
+	Images []image `json:"image"`
+}
+
+// productDetails describes the part of the product details response that we wish to process.
+type productDetails struct {
+	ProductID string       `json:"identifier"`
+	Name      string       `json:"name"`
+	SKUs      []skuDetails `json:"model"`
+}
+
+// metadata is the human-readable information about a product, for display and notifications.
+type metadata struct {
+	Name     string
+	Color    string
+	Size     string
+	Price    float64
+	Currency string
+	ImageURL string
+}
+
+// String returns a short description of the product, ie. its name followed by colour and size, if known.
+func (md metadata) String() string {
+	if v := md.variant(); v != "" {
+		return fmt.Sprintf("%s (%s)", md.Name, v)
+	}
+	return md.Name
+}
+
+// variant returns the colour and size of the product, if known.
+func (md metadata) variant() string {
+	parts := make([]string, 0, 2)
+	if md.Color != "" {
+		parts = append(parts, md.Color)
+	}
+	if md.Size != "" {
+		parts = append(parts, "size "+md.Size)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatPrice returns the price followed by its currency, or an empty string if the price is unknown.
+func (md metadata) formatPrice() string {
+	if md.Price == 0 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", md.Price, md.Currency))
+}
+
+// metadata returns the metadata for the given SKU. If the SKU is empty or unknown, the first SKU is used, except
+// for its size, which would be misleading.
+func (d productDetails) metadata(sku string) metadata {
+	md := metadata{Name: d.Name}
+	if len(d.SKUs) == 0 {
+		return md
+	}
+	s := d.SKUs[0]
+	found := false
+	for _, candidate := range d.SKUs {
+		if candidate.SKU == sku {
+			s, found = candidate, true
+			break
+		}
+	}
+	if md.Name == "" {
+		md.Name = s.Name
+	}
+	md.Color = s.Color
+	if found {
+		md.Size = s.Size
+	}
+	md.Price = float64(s.Offers.Price)
+	md.Currency = s.Offers.Currency
+	if len(s.Images) > 0 {
+		md.ImageURL = s.Images[0].URL
+	}
+	return md
+}
+
+// parseDetails converts a product details response body into productDetails.
+func parseDetails(bytes []byte) (productDetails, error) {
+	var details productDetails
+	if err := json.Unmarshal(bytes, &details); err != nil {
+		return productDetails{}, decodeError(bytes, err)
+	}
+	if len(details.SKUs) == 0 {
+		return productDetails{}, &apiError{kind: errSchema, body: snippet(bytes), err: errors.New("no SKU's available")}
+	}
+	return details, nil
+}
+
+// details fetches product details, including the list of SKU's, for the given product.
+// Details are cached per country for detailsTTL, since prices differ between countries. Failures are cached for
+// detailsRetry.
+func (m *MainLoop) details(p product) (productDetails, error) {
+	pID := p.productID()
+	if pID == "" {
+		return productDetails{}, errors.New("invalid URL or no product ID")
+	}
+
+	key := cacheKey(m.country(p).Code(), pID)
+	release := m.detailsCache.acquire(key)
+	defer release()
+	cached, fresh, ok := m.detailsCache.get(key, detailsTTL, m.now())
+	if ok && cached.err != nil {
+		if m.now().Sub(cached.fetchedAt) < detailsRetry {
+			return productDetails{}, cached.err
+		}
+	} else if fresh {
+		return parseDetails(cached.body)
+	}
+
+	fetchedAt := m.now()
+	bytes, err := m.getBody(p.URL, m.country(p).Code(), m.apiURL(endpointProduct, "{locale}", m.country(p).Code(), "{id}", pID))
+	var details productDetails
+	if err == nil {
+		details, err = parseDetails(bytes)
+	}
+	if err != nil {
+		m.detailsCache.put(key, cachedResponse{err: err, fetchedAt: fetchedAt})
+		return productDetails{}, err
+	}
+	m.detailsCache.put(key, cachedResponse{body: bytes, fetchedAt: fetchedAt})
+	return details, nil
+}
+
+// metadata fetches the metadata for the given product, see details.
+func (m *MainLoop) metadata(p product) (metadata, error) {
+	details, err := m.details(p)
+	if err != nil {
+		return metadata{}, err
+	}
+	return details.metadata(p.SKU()), nil
+}
+
+// sizeSKUs returns the SKU's that match the given sizes, in the order the sizes were given.
+// Sizes are matched against the size label of each SKU, ignoring case and surrounding whitespace.
+// sizeSKUs returns an error listing the available sizes if any of the sizes could not be found.
+func (d productDetails) sizeSKUs(sizes []string) ([]string, error) {
+	skus := make([]string, 0, len(sizes))
+	for _, size := range sizes {
+		size = strings.TrimSpace(size)
+		found := false
+		for _, sku := range d.SKUs {
+			if strings.EqualFold(strings.TrimSpace(sku.Size), size) {
+				skus = append(skus, sku.SKU)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return []string{}, fmt.Errorf("size %q not found, available sizes are: %s", size, strings.Join(d.sizes(), ", "))
+		}
+	}
+	return skus, nil
+}
+
+// sizes returns the size labels of all SKU's.
+func (d productDetails) sizes() []string {
+	sizes := make([]string, 0, len(d.SKUs))
+	for _, sku := range d.SKUs {
+		if sku.Size != "" {
+			sizes = append(sizes, sku.Size)
+		}
+	}
+	return sizes
+}
+
+// resolveSizes converts products with sizes into products with the matching SKU's.
+// Products without sizes are returned as-is. Products whose sizes can't be resolved are left out, and reported via
+// a pFileError, just like lines in the P-file that can't be parsed.
+func (m *MainLoop) resolveSizes(ps []product) ([]product, error) {
+	resolved := make([]product, 0, len(ps))
+	var perr pFileError
+	for _, p := range ps {
+		if p.Sizes == "" {
+			resolved = append(resolved, p)
+			continue
+		}
+		details, err := m.details(p)
+		if err != nil {
+			perr = append(perr, lineError{line: p.line, err: fmt.Errorf("unable to resolve sizes: %w", err)})
+			continue
+		}
+		skus, err := details.sizeSKUs(strings.Split(p.Sizes, ","))
+		if err != nil {
+			perr = append(perr, lineError{line: p.line, err: err})
+			continue
+		}
+		// Sizes are appended to SKU's already listed in the URL.
+		base := strings.Split(p.URL, "#")[0]
+		p.URL = base + "#" + strings.Join(append(p.SKUs(), skus...), ",")
+		p.Sizes = ""
+		resolved = append(resolved, p.expand()...)
+	}
+	if len(perr) > 0 {
+		return resolved, perr
+	}
+	return resolved, nil
+}
+
+// PrintSKUs fetches the list of SKU's for the product identified by rawURL, and writes it as a table to w.
+func (m *MainLoop) PrintSKUs(w io.Writer, rawURL string) error {
+	p := product{URL: rawURL}
+	if !p.Valid() {
+		return errors.New("invalid product URL")
+	}
+	details, err := m.details(p)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(w, "%s (%s)\n\n", details.Name, p.productID())
+	t := tablewriter.NewWriter(w)
+	t.SetHeader([]string{"SKU", "Size", "Colour", "Price"})
+	for _, sku := range details.SKUs {
+		t.Append([]string{sku.SKU, sku.Size, sku.Color, details.metadata(sku.SKU).formatPrice()})
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,152 @@
+package vuitton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseDetails(t *testing.T) {
+	tests := []struct {
+		file  string
+		pID   string
+		name  string
+		skus  string
+		sizes string
+	}{
+		{"product_nvprod3130266v.json", "nvprod3130266v", "Charlie Sneaker", "[1A9JN6 1A9JN7 1A9JN8 1A9JNC]", "[6 7.5 8 9]"},
+		{"product_nvprod3390166v.json", "nvprod3390166v", "Croisillon Shawl", "[M77459 M77460]", "[]"},
+	}
+
+	for _, tt := range tests {
+		bytes, err := ioutil.ReadFile("testdata/" + tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		details, err := parseDetails(bytes)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.file, err.Error())
+			continue
+		}
+		if details.ProductID != tt.pID || details.Name != tt.name {
+			t.Errorf("%s: expected %s %q, got %s %q", tt.file, tt.pID, tt.name, details.ProductID, details.Name)
+		}
+		skus := make([]string, 0, len(details.SKUs))
+		for _, sku := range details.SKUs {
+			skus = append(skus, sku.SKU)
+		}
+		if fmt.Sprint(skus) != tt.skus {
+			t.Errorf("%s: expected SKU's %s, got %v", tt.file, tt.skus, skus)
+		}
+		if fmt.Sprint(details.sizes()) != tt.sizes {
+			t.Errorf("%s: expected sizes %s, got %v", tt.file, tt.sizes, details.sizes())
+		}
+	}
+
+	if _, err := parseDetails([]byte(`{"identifier": "nvprod3130266v", "model": []}`)); err == nil {
+		t.Error("expected error for product without SKU's")
+	}
+	if _, err := parseDetails([]byte(`<html></html>`)); err == nil {
+		t.Error("expected error for HTML response")
+	}
+}
+
+func TestSizeSKUs(t *testing.T) {
+	bytes, err := ioutil.ReadFile("testdata/product_nvprod3130266v.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err := parseDetails(bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		in  []string
+		out string
+		err bool
+	}{
+		{[]string{"8"}, "[1A9JN8]", false},
+		{[]string{"9", " 7.5"}, "[1A9JNC 1A9JN7]", false},
+		{[]string{"8", "10"}, "[]", true},
+		{[]string{""}, "[]", true},
+	}
+
+	for _, tt := range tests {
+		skus, err := details.sizeSKUs(tt.in)
+		if (err != nil) != tt.err {
+			t.Errorf("%v: expected error %t, got %v", tt.in, tt.err, err)
+		}
+		if fmt.Sprint(skus) != tt.out {
+			t.Errorf("%v: expected %s, got %v", tt.in, tt.out, skus)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	tests := []struct {
+		file, sku string
+		desc      string
+		price     string
+		image     bool
+	}{
+		{"product_nvprod3130266v.json", "1A9JN8", "Charlie Sneaker (Black, size 8)", "1090.00 EUR", true},
+		{"product_nvprod3130266v.json", "", "Charlie Sneaker (Black)", "1090.00 EUR", true},
+		{"product_nvprod3130266v.json", "XXXXXX", "Charlie Sneaker (Black)", "1090.00 EUR", true},
+		{"product_nvprod3390166v.json", "M77460", "Croisillon Shawl (Pink)", "495.00 EUR", true},
+	}
+
+	for _, tt := range tests {
+		bytes, err := ioutil.ReadFile("testdata/" + tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		details, err := parseDetails(bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md := details.metadata(tt.sku)
+		if md.String() != tt.desc {
+			t.Errorf("%s#%s: expected %q, got %q", tt.file, tt.sku, tt.desc, md.String())
+		}
+		if md.formatPrice() != tt.price {
+			t.Errorf("%s#%s: expected price %q, got %q", tt.file, tt.sku, tt.price, md.formatPrice())
+		}
+		if (md.ImageURL != "") != tt.image {
+			t.Errorf("%s#%s: expected image %t, got %q", tt.file, tt.sku, tt.image, md.ImageURL)
+		}
+	}
+}
+
+func TestDetailsRetry(t *testing.T) {
+	requests := 0
+	m := newTestLoop(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	clock := newFakeClock()
+	m.Clock = clock
+	p := product{URL: "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8"}
+
+	// A failure is reused until detailsRetry has passed, and then retried.
+	tests := []struct {
+		elapsed  time.Duration
+		requests int
+	}{
+		{0, 1},
+		{time.Minute, 1},
+		{detailsRetry - time.Minute, 2},
+		{time.Minute, 2},
+	}
+	for i, tt := range tests {
+		clock.Advance(tt.elapsed)
+		if _, err := m.metadata(p); err == nil {
+			t.Errorf("%d: expected error, got nil", i)
+		}
+		if requests != tt.requests {
+			t.Errorf("%d: expected %d requests, got %d", i, tt.requests, requests)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package vuitton
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// lvAPIBase is the scheme and host of Louis Vuitton's API. Endpoint templates are relative to it.
+const lvAPIBase = "https://api.louisvuitton.com"
+
+// Names of the API's endpoints, as used to override their templates.
+const (
+	endpointAvailability      = "availability"
+	endpointProduct           = "product"
+	endpointModel             = "model"
+	endpointSearch            = "search"
+	endpointCategory          = "category"
+	endpointStoreAvailability = "storeAvailability"
+	endpointStores            = "stores"
+)
+
+// endpoint is the default path template of an API endpoint, and the placeholders its template must contain.
+type endpoint struct {
+	template     string
+	placeholders []string
+}
+
+// endpoints maps endpoint names to their defaults.
+var endpoints = map[string]endpoint{
+	endpointAvailability:      {lvURL, []string{"{locale}", "{id}"}},
+	endpointProduct:           {lvProductURL, []string{"{locale}", "{id}"}},
+	endpointModel:             {lvModelURL, []string{"{locale}", "{model}"}},
+	endpointSearch:            {lvSearchURL, []string{"{locale}", "{query}"}},
+	endpointCategory:          {lvCategoryURL, []string{"{locale}", "{category}"}},
+	endpointStoreAvailability: {lvStoreAvailabilityURL, []string{"{locale}", "{id}", "{stores}"}},
+	endpointStores:            {lvStoresURL, []string{"{locale}"}},
+}
+
+// placeholderRegExp matches a placeholder in an endpoint template, eg. "{locale}".
+var placeholderRegExp = regexp.MustCompile(`{[^{}]*}`)
+
+// LoadEndpoints reads endpoint templates from a JSON file, which maps endpoint names to templates, eg.
+// {"availability": "/v2/{locale}/availability/{id}"}. The templates are not validated, see ValidateEndpoints.
+func LoadEndpoints(filename string) (map[string]string, error) {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var templates map[string]string
+	if err := json.Unmarshal(bytes, &templates); err != nil {
+		return nil, fmt.Errorf("invalid endpoints in %s: %w", filename, err)
+	}
+	return templates, nil
+}
+
+// ValidateEndpoints checks an API base and templates that override the defaults. The base must be an absolute http or
+// https URL without query or fragment. Each template must belong to a known endpoint, start with "/", and contain
+// exactly the placeholders of that endpoint. An empty base and no templates are valid, and select the defaults.
+func ValidateEndpoints(base string, templates map[string]string) error {
+	if base != "" {
+		u, err := url.Parse(base)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid API base %q, must be an http or https URL", base)
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("invalid API base %q, must not have a query or fragment", base)
+		}
+	}
+
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tmpl := templates[name]
+		e, ok := endpoints[name]
+		if !ok {
+			return fmt.Errorf("unknown endpoint %q, must be one of %s", name, endpointNames())
+		}
+		if !strings.HasPrefix(tmpl, "/") {
+			return fmt.Errorf("invalid template %q for endpoint %s, must start with /", tmpl, name)
+		}
+		found := placeholderRegExp.FindAllString(tmpl, -1)
+		for _, p := range found {
+			if !contains(e.placeholders, p) {
+				return fmt.Errorf("invalid template %q for endpoint %s, unknown placeholder %s", tmpl, name, p)
+			}
+		}
+		for _, p := range e.placeholders {
+			if !contains(found, p) {
+				return fmt.Errorf("invalid template %q for endpoint %s, missing placeholder %s", tmpl, name, p)
+			}
+		}
+	}
+	return nil
+}
+
+// endpointNames returns the names of all endpoints, sorted and comma-separated.
+func endpointNames() string {
+	names := make([]string, 0, len(endpoints))
+	for name := range endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// contains returns true if s is one of ss.
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// apiURL returns the URL of the named endpoint, with its placeholders replaced by the given pairs of placeholder and
+// value, eg. apiURL(endpointStores, "{locale}", "eng-nl"). Values must already be escaped. MainLoop.APIBase and
+// MainLoop.Endpoints override the defaults.
+func (m *MainLoop) apiURL(name string, pairs ...string) string {
+	tmpl, ok := m.Endpoints[name]
+	if !ok {
+		tmpl = endpoints[name].template
+	}
+	base := m.APIBase
+	if base == "" {
+		base = lvAPIBase
+	}
+	return strings.TrimSuffix(base, "/") + strings.NewReplacer(pairs...).Replace(tmpl)
+}
@@ -0,0 +1,89 @@
+package vuitton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateEndpoints(t *testing.T) {
+	tests := []struct {
+		base      string
+		templates map[string]string
+		expected  string
+	}{
+		{"", nil, ""},
+		{"http://localhost:8080", map[string]string{"availability": "/v2/{locale}/stock/{id}"}, ""},
+		{"https://mirror.example.com/lv/", map[string]string{"stores": "/stores?locale={locale}"}, ""},
+		{"localhost:8080", nil, "must be an http or https URL"},
+		{"ftp://localhost", nil, "must be an http or https URL"},
+		{"http://localhost:8080?x=1", nil, "must not have a query or fragment"},
+		{"", map[string]string{"basket": "/api/{locale}/basket"}, "unknown endpoint"},
+		{"", map[string]string{"availability": "api/{locale}/catalog/availability/{id}"}, "must start with /"},
+		{"", map[string]string{"availability": "/api/{locale}/catalog/availability"}, "missing placeholder {id}"},
+		{"", map[string]string{"stores": "/api/{locale}/stores/{id}"}, "unknown placeholder {id}"},
+	}
+	for _, test := range tests {
+		err := ValidateEndpoints(test.base, test.templates)
+		if test.expected == "" && err != nil {
+			t.Errorf("unexpected error for %q and %v: %s", test.base, test.templates, err)
+		}
+		if test.expected != "" && (err == nil || !strings.Contains(err.Error(), test.expected)) {
+			t.Errorf("expected error containing %q for %q and %v, got %v", test.expected, test.base, test.templates, err)
+		}
+	}
+
+	// The defaults are valid.
+	defaults := make(map[string]string)
+	for name, e := range endpoints {
+		defaults[name] = e.template
+	}
+	if err := ValidateEndpoints(lvAPIBase, defaults); err != nil {
+		t.Errorf("unexpected error for defaults: %s", err)
+	}
+}
+
+func TestEndpointOverride(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "endpoints.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"availability": "/v2/{locale}/stock/{id}"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := LoadEndpoints(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := MainLoop{APIBase: "http://localhost:8080", Endpoints: templates}
+
+	tests := []struct {
+		actual   string
+		expected string
+	}{
+		{m.apiURL(endpointAvailability, "{locale}", "eng-nl", "{id}", "nvprod3130266v"), "http://localhost:8080/v2/eng-nl/stock/nvprod3130266v"},
+		{m.apiURL(endpointStores, "{locale}", "eng-nl"), "http://localhost:8080/api/eng-nl/stores"},
+	}
+	for _, test := range tests {
+		if test.actual != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, test.actual)
+		}
+	}
+}
+
+func TestEndpointOverrideAcceptLanguage(t *testing.T) {
+	var path, language string
+	m := newTestLoop(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, language = r.URL.Path, r.Header.Get("accept-language")
+		_, _ = fmt.Fprint(w, `{"skuAvailability": [{"skuId": "1A9JN8", "exists": true, "inStock": true}]}`)
+	}))
+	m.Endpoints = map[string]string{endpointAvailability: "/v2/{locale}/stock/{id}"}
+
+	p := product{URL: "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8", Country: "DE"}
+	if _, err := m.availability(p); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/v2/deu-de/stock/nvprod3130266v" || language != "de-DE,de;q=0.9,en;q=0.8" {
+		t.Errorf("expected German request to overridden path, got %q with %q", path, language)
+	}
+}
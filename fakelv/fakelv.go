@@ -0,0 +1,217 @@
+// Package fakelv provides a fake Louis Vuitton API for local development and integration tests. It serves the
+// availability endpoint for the products it is given, and can be told to flip stock levels, slow down, rate limit
+// or send malformed responses, either by calling its methods or via its control endpoints:
+//
+//	POST /control/stock?product=nvprod3130266v&sku=1A9JN8&instock=true
+//	POST /control/latency?duration=500ms
+//	POST /control/ratelimit?count=3
+//	POST /control/malformed?count=1
+//	GET  /control/state
+package fakelv
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// availabilityRegExp matches the path of the availability endpoint, and captures the product ID.
+var availabilityRegExp = regexp.MustCompile(`^/api/[a-z]{3}-[a-z]{2}/catalog/availability/([^/]+)$`)
+
+// SKU is the stock level of a single SKU.
+type SKU struct {
+	ID      string `json:"skuId"`
+	Exists  bool   `json:"exists"`
+	InStock bool   `json:"inStock"`
+}
+
+// Server is a fake Louis Vuitton API. The zero value is not usable, use New. Server is safe for concurrent use.
+type Server struct {
+	sync.Mutex                  // Protects the field(s) below.
+	products   map[string][]SKU // Key is product ID, SKU's are kept in the order they were added.
+	latency    time.Duration    // Delay before each API response.
+	rateLimit  int              // Number of upcoming API requests to answer with 429 Too Many Requests.
+	malformed  int              // Number of upcoming API requests to answer with a body that isn't JSON.
+	requests   int              // Number of API requests served, excluding control requests.
+}
+
+// New returns a fake API without any products.
+func New() *Server {
+	return &Server{products: make(map[string][]SKU)}
+}
+
+// Start starts serving the fake API on a local port, see httptest.NewServer. The caller must close the server.
+func (s *Server) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+// AddProduct adds a product with the given SKU's, all out of stock. Without SKU's, the product gets a single SKU with
+// the product ID as its ID. Adding a product that already exists replaces it.
+func (s *Server) AddProduct(productID string, skus ...string) {
+	if len(skus) == 0 {
+		skus = []string{productID}
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.products[productID] = make([]SKU, 0, len(skus))
+	for _, id := range skus {
+		s.products[productID] = append(s.products[productID], SKU{ID: id, Exists: true})
+	}
+}
+
+// SetStock sets the stock level of a SKU, adding the product and SKU if they don't exist. An empty SKU sets the stock
+// level of every SKU of the product.
+func (s *Server) SetStock(productID, sku string, inStock bool) {
+	s.Lock()
+	defer s.Unlock()
+	skus := s.products[productID]
+	found := false
+	for i := range skus {
+		if sku == "" || skus[i].ID == sku {
+			skus[i].InStock = inStock
+			found = true
+		}
+	}
+	if !found {
+		if sku == "" {
+			sku = productID
+		}
+		skus = append(skus, SKU{ID: sku, Exists: true, InStock: inStock})
+	}
+	s.products[productID] = skus
+}
+
+// SetLatency delays every API response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.latency = d
+}
+
+// RateLimit answers the next n API requests with 429 Too Many Requests.
+func (s *Server) RateLimit(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.rateLimit = n
+}
+
+// Malform answers the next n API requests with a body that isn't JSON.
+func (s *Server) Malform(n int) {
+	s.Lock()
+	defer s.Unlock()
+	s.malformed = n
+}
+
+// Requests returns the number of API requests served so far.
+func (s *Server) Requests() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.requests
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/control/stock", "/control/latency", "/control/ratelimit", "/control/malformed":
+		s.control(w, r)
+	case "/control/state":
+		s.state(w)
+	default:
+		s.availability(w, r)
+	}
+}
+
+// availability serves the availability endpoint.
+func (s *Server) availability(w http.ResponseWriter, r *http.Request) {
+	match := availabilityRegExp.FindStringSubmatch(r.URL.Path)
+	if match == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.Lock()
+	s.requests++
+	latency := s.latency
+	rateLimited := s.rateLimit > 0
+	if rateLimited {
+		s.rateLimit--
+	}
+	malformed := !rateLimited && s.malformed > 0
+	if malformed {
+		s.malformed--
+	}
+	skus, ok := s.products[match[1]]
+	skus = append([]SKU{}, skus...)
+	s.Unlock()
+
+	time.Sleep(latency)
+	switch {
+	case rateLimited:
+		http.Error(w, `{"error": "too many requests"}`, http.StatusTooManyRequests)
+	case malformed:
+		w.Header().Set("content-type", "text/html")
+		_, _ = fmt.Fprint(w, "<html><body>Please verify you are a human</body></html>")
+	case !ok:
+		http.NotFound(w, r)
+	default:
+		w.Header().Set("content-type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string][]SKU{"skuAvailability": skus})
+	}
+}
+
+// control serves the control endpoints.
+func (s *Server) control(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	switch r.URL.Path {
+	case "/control/stock":
+		product := q.Get("product")
+		inStock, err := strconv.ParseBool(q.Get("instock"))
+		if product == "" || err != nil {
+			http.Error(w, "need a product and instock=true or false", http.StatusBadRequest)
+			return
+		}
+		s.SetStock(product, q.Get("sku"), inStock)
+	case "/control/latency":
+		d, err := time.ParseDuration(q.Get("duration"))
+		if err != nil || d < 0 {
+			http.Error(w, "need a duration, eg. 500ms", http.StatusBadRequest)
+			return
+		}
+		s.SetLatency(d)
+	default:
+		n, err := strconv.Atoi(q.Get("count"))
+		if err != nil || n < 0 {
+			http.Error(w, "need a count", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/control/ratelimit" {
+			s.RateLimit(n)
+		} else {
+			s.Malform(n)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// state serves the current stock levels and settings as JSON.
+func (s *Server) state(w http.ResponseWriter) {
+	s.Lock()
+	defer s.Unlock()
+	w.Header().Set("content-type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Products  map[string][]SKU `json:"products"`
+		Latency   string           `json:"latency"`
+		RateLimit int              `json:"rateLimit"`
+		Malformed int              `json:"malformed"`
+		Requests  int              `json:"requests"`
+	}{s.products, s.latency.String(), s.rateLimit, s.malformed, s.requests})
+}
@@ -0,0 +1,106 @@
+package fakelv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	s := New()
+	s.AddProduct("nvprod3130266v", "1A9JN8", "1A9JNC")
+	srv := s.Start()
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	post := func(path string, expected int) {
+		t.Helper()
+		resp, err := http.Post(srv.URL+path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("expected status code %d for %s, got %d", expected, path, resp.StatusCode)
+		}
+	}
+	const path = "/api/eng-nl/catalog/availability/nvprod3130266v"
+
+	tests := []struct {
+		control  string
+		status   int
+		expected string
+	}{
+		{"", 200, `{"skuAvailability":[{"skuId":"1A9JN8","exists":true,"inStock":false},{"skuId":"1A9JNC","exists":true,"inStock":false}]}` + "\n"},
+		{"/control/stock?product=nvprod3130266v&sku=1A9JNC&instock=true", 200, `{"skuAvailability":[{"skuId":"1A9JN8","exists":true,"inStock":false},{"skuId":"1A9JNC","exists":true,"inStock":true}]}` + "\n"},
+		{"/control/stock?product=nvprod3130266v&instock=true", 200, `{"skuAvailability":[{"skuId":"1A9JN8","exists":true,"inStock":true},{"skuId":"1A9JNC","exists":true,"inStock":true}]}` + "\n"},
+		{"/control/ratelimit?count=1", 429, `{"error": "too many requests"}` + "\n"},
+		{"/control/malformed?count=1", 200, "<html><body>Please verify you are a human</body></html>"},
+		{"/control/latency?duration=0s", 200, `{"skuAvailability":[{"skuId":"1A9JN8","exists":true,"inStock":true},{"skuId":"1A9JNC","exists":true,"inStock":true}]}` + "\n"},
+	}
+	for _, test := range tests {
+		if test.control != "" {
+			post(test.control, http.StatusNoContent)
+		}
+		status, body := get(path)
+		if status != test.status || body != test.expected {
+			t.Errorf("expected %d %q after %q, got %d %q", test.status, test.expected, test.control, status, body)
+		}
+	}
+
+	if status, _ := get("/api/eng-nl/catalog/availability/nvprod0000000v"); status != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown product, got %d", status)
+	}
+	if s.Requests() != len(tests)+1 {
+		t.Errorf("expected %d requests, got %d", len(tests)+1, s.Requests())
+	}
+
+	for _, control := range []string{"/control/stock?instock=true", "/control/stock?product=nvprod3130266v", "/control/latency?duration=soon", "/control/ratelimit?count=-1"} {
+		post(control, http.StatusBadRequest)
+	}
+	if status, _ := get("/control/stock?product=nvprod3130266v&instock=false"); status != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET on control endpoint, got %d", status)
+	}
+
+	_, body := get("/control/state")
+	var state struct {
+		Products map[string][]SKU `json:"products"`
+		Requests int              `json:"requests"`
+	}
+	if err := json.Unmarshal([]byte(body), &state); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(state.Products) != "map[nvprod3130266v:[{1A9JN8 true true} {1A9JNC true true}]]" {
+		t.Errorf("unexpected state %s", body)
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	s := New()
+	s.AddProduct("nvprod3430052v")
+	s.SetLatency(50 * time.Millisecond)
+	srv := s.Start()
+	defer srv.Close()
+
+	start := time.Now()
+	resp, err := http.Get(srv.URL + "/api/eng-nl/catalog/availability/nvprod3430052v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected response after at least 50ms, got %s", elapsed)
+	}
+}
@@ -1,18 +1,20 @@
 module vuitton
 
-go 1.17
+go 1.18
 
-require github.com/gen2brain/beeep v0.0.0-20210529141713-5586760f0cc1
+require (
+	github.com/atomicgo/cursor v0.0.1
+	github.com/gen2brain/beeep v0.0.0-20210529141713-5586760f0cc1
+	github.com/olekukonko/tablewriter v0.0.5
+)
 
 require (
-	github.com/atomicgo/cursor v0.0.1 // indirect
 	github.com/go-toast/toast v0.0.0-20190211030409-01e6764cf0a4 // indirect
 	github.com/godbus/dbus/v5 v5.0.3 // indirect
 	github.com/gopherjs/gopherjs v0.0.0-20180825215210-0210a2f0f73c // indirect
 	github.com/gopherjs/gopherwasm v1.1.0 // indirect
 	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/nu7hatch/gouuid v0.0.0-20131221200532-179d4d0c4d8d // indirect
-	github.com/olekukonko/tablewriter v0.0.5 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/tadvi/systray v0.0.0-20190226123456-11a2b8fa57af // indirect
 	golang.org/x/sys v0.0.0-20200302150141-5c8b2ff67527 // indirect
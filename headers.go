@@ -0,0 +1,149 @@
+package vuitton
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// HeaderProfile describes the headers sent by a particular browser. The accept-language header isn't part of the
+// profile, as it follows the country being checked.
+type HeaderProfile struct {
+	Name      string            `json:"name"`
+	UserAgent string            `json:"userAgent"`
+	SecCHUA   string            `json:"secChUa"`  // Client hints brand list. Empty for browsers that don't send client hints.
+	Platform  string            `json:"platform"` // Client hints platform, eg. "Windows".
+	Mobile    bool              `json:"mobile"`
+	Headers   map[string]string `json:"headers"` // Additional headers, which override the defaults.
+}
+
+// DefaultHeaderProfiles are used when no profiles are configured.
+var DefaultHeaderProfiles = []HeaderProfile{
+	{
+		Name:      "chrome-windows",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+		SecCHUA:   `"Google Chrome";v="129", "Not=A?Brand";v="8", "Chromium";v="129"`,
+		Platform:  "Windows",
+	},
+	{
+		Name:      "chrome-mac",
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+		SecCHUA:   `"Google Chrome";v="129", "Not=A?Brand";v="8", "Chromium";v="129"`,
+		Platform:  "macOS",
+	},
+	{
+		Name:      "firefox-windows",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
+	},
+	{
+		Name:      "safari-mac",
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15",
+	},
+}
+
+// languages maps the language part of API locales to ISO 639-1 language tags.
+var languages = map[string]string{
+	"ara": "ar",
+	"deu": "de",
+	"eng": "en",
+	"esp": "es",
+	"fra": "fr",
+	"ita": "it",
+	"jpn": "ja",
+	"kor": "ko",
+	"por": "pt",
+	"rus": "ru",
+	"tha": "th",
+	"zhs": "zh",
+	"zht": "zh",
+}
+
+// LoadHeaderProfiles reads a JSON array of header profiles from the given file. Each profile must have a unique name
+// and a user agent.
+func LoadHeaderProfiles(filename string) ([]HeaderProfile, error) {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var profiles []HeaderProfile
+	if err := json.Unmarshal(bytes, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid header profiles in %s: %w", filename, err)
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("no header profiles in %s", filename)
+	}
+	names := make(map[string]bool, len(profiles))
+	for i, hp := range profiles {
+		if hp.Name == "" || hp.UserAgent == "" {
+			return nil, fmt.Errorf("header profile %d in %s needs a name and a user agent", i+1, filename)
+		}
+		if names[hp.Name] {
+			return nil, fmt.Errorf("duplicate header profile %q in %s", hp.Name, filename)
+		}
+		names[hp.Name] = true
+	}
+	return profiles, nil
+}
+
+// SelectHeaderProfile returns the profile with the given name.
+func SelectHeaderProfile(profiles []HeaderProfile, name string) (HeaderProfile, error) {
+	for _, hp := range profiles {
+		if hp.Name == name {
+			return hp, nil
+		}
+	}
+	return HeaderProfile{}, fmt.Errorf("unknown header profile %q", name)
+}
+
+// headerProfile returns the header profile to use for the next request. Requests rotate through
+// MainLoop.HeaderProfiles, or DefaultHeaderProfiles if none are configured.
+func (m *MainLoop) headerProfile() HeaderProfile {
+	profiles := m.HeaderProfiles
+	if len(profiles) == 0 {
+		profiles = DefaultHeaderProfiles[:1]
+	}
+	n := atomic.AddUint32(&m.headerCount, 1)
+	return profiles[int(n-1)%len(profiles)]
+}
+
+// acceptLanguage returns the accept-language header for the given API locale, eg. "fr-FR,fr;q=0.9,en;q=0.8" for
+// "fra-fr". Unknown locales fall back to English.
+func acceptLanguage(locale string) string {
+	parts := strings.Split(locale, "-")
+	lang, ok := languages[parts[0]]
+	if len(parts) != 2 || !ok {
+		return "en-US,en;q=0.9"
+	}
+	tag := lang + "-" + strings.ToUpper(parts[1])
+	if lang == "en" {
+		return tag + ",en;q=0.9"
+	}
+	return tag + "," + lang + ";q=0.9,en;q=0.8"
+}
+
+// setCommonHeaders adds common headers to API requests, as sent by the browser described by hp. The accept-language
+// header follows the given API locale, which is the locale of the country being checked.
+func setCommonHeaders(req *http.Request, hp HeaderProfile, locale string) {
+	req.Header.Set("accept", "application/json, text/plain, */*")
+	req.Header.Set("accept-language", acceptLanguage(locale))
+	req.Header.Set("dnt", "1")
+	req.Header.Set("user-agent", hp.UserAgent)
+	if hp.SecCHUA != "" {
+		mobile := "?0"
+		if hp.Mobile {
+			mobile = "?1"
+		}
+		req.Header.Set("sec-ch-ua", hp.SecCHUA)
+		req.Header.Set("sec-ch-ua-mobile", mobile)
+		req.Header.Set("sec-ch-ua-platform", `"`+hp.Platform+`"`)
+	}
+	req.Header.Set("sec-fetch-site", "same-site")
+	req.Header.Set("sec-fetch-mode", "cors")
+	req.Header.Set("sec-fetch-dest", "empty")
+	for k, v := range hp.Headers {
+		req.Header.Set(k, v)
+	}
+}
@@ -0,0 +1,129 @@
+package vuitton
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		locale   string
+		expected string
+	}{
+		{"eng-nl", "en-NL,en;q=0.9"},
+		{"eng-gb", "en-GB,en;q=0.9"},
+		{"fra-fr", "fr-FR,fr;q=0.9,en;q=0.8"},
+		{"jpn-jp", "ja-JP,ja;q=0.9,en;q=0.8"},
+		{"zht-tw", "zh-TW,zh;q=0.9,en;q=0.8"},
+		{"xyz-zz", "en-US,en;q=0.9"},
+		{"", "en-US,en;q=0.9"},
+	}
+	for _, test := range tests {
+		if actual := acceptLanguage(test.locale); actual != test.expected {
+			t.Errorf("expected %q for %q, got %q", test.expected, test.locale, actual)
+		}
+	}
+}
+
+func TestSetCommonHeaders(t *testing.T) {
+	tests := []struct {
+		url      string
+		locale   string
+		profile  HeaderProfile
+		expected map[string]string
+	}{
+		{
+			"https://api.louisvuitton.com/api/deu-de/catalog/availability/nvprod3130266v",
+			"deu-de",
+			DefaultHeaderProfiles[0],
+			map[string]string{
+				"accept-language":    "de-DE,de;q=0.9,en;q=0.8",
+				"user-agent":         DefaultHeaderProfiles[0].UserAgent,
+				"sec-ch-ua":          DefaultHeaderProfiles[0].SecCHUA,
+				"sec-ch-ua-mobile":   "?0",
+				"sec-ch-ua-platform": `"Windows"`,
+			},
+		},
+		{
+			"https://api.louisvuitton.com/api/eng-us/catalog/search?q=neverfull",
+			"eng-us",
+			HeaderProfile{UserAgent: "Firefox", Headers: map[string]string{"dnt": "0"}},
+			map[string]string{
+				"accept-language":    "en-US,en;q=0.9",
+				"user-agent":         "Firefox",
+				"sec-ch-ua":          "",
+				"sec-ch-ua-platform": "",
+				"dnt":                "0",
+			},
+		},
+		{
+			// The locale doesn't depend on the path, which may be overridden, see ValidateEndpoints.
+			"http://localhost:8080/v2/fra-fr/stock/nvprod3130266v",
+			"fra-fr",
+			DefaultHeaderProfiles[0],
+			map[string]string{"accept-language": "fr-FR,fr;q=0.9,en;q=0.8"},
+		},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, test.url, nil)
+		setCommonHeaders(req, test.profile, test.locale)
+		for k, v := range test.expected {
+			if actual := req.Header.Get(k); actual != v {
+				t.Errorf("expected %s %q for %s, got %q", k, v, test.url, actual)
+			}
+		}
+	}
+}
+
+func TestLoadHeaderProfiles(t *testing.T) {
+	profiles, err := LoadHeaderProfiles("testdata/headers.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hp, err := SelectHeaderProfile(profiles, "chrome-android")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hp.Mobile || hp.Platform != "Android" || hp.Headers["dnt"] != "0" {
+		t.Errorf("unexpected profile %+v", hp)
+	}
+	if _, err := SelectHeaderProfile(profiles, "opera-linux"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+
+	dir := t.TempDir()
+	tests := []string{
+		`[]`,
+		`[{"name": "no-agent"}]`,
+		`[{"userAgent": "Mozilla/5.0"}]`,
+		`[{"name": "a", "userAgent": "Mozilla/5.0"}, {"name": "a", "userAgent": "Mozilla/5.0"}]`,
+		`{"name": "a"}`,
+	}
+	for i, test := range tests {
+		filename := filepath.Join(dir, "headers.json")
+		if err := ioutil.WriteFile(filename, []byte(test), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadHeaderProfiles(filename); err == nil {
+			t.Errorf("expected error for test %d: %s", i+1, test)
+		}
+	}
+}
+
+func TestHeaderProfileRotation(t *testing.T) {
+	m := MainLoop{}
+	if hp := m.headerProfile(); hp.Name != DefaultHeaderProfiles[0].Name {
+		t.Errorf("expected %q without profiles, got %q", DefaultHeaderProfiles[0].Name, hp.Name)
+	}
+
+	m.HeaderProfiles = []HeaderProfile{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	names := ""
+	for i := 0; i < 6; i++ {
+		names += m.headerProfile().Name
+	}
+	if names != "bcabca" {
+		t.Errorf("expected %q, got %q", "bcabca", names)
+	}
+}
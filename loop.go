@@ -1,17 +1,18 @@
 package vuitton
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
-
-	"github.com/atomicgo/cursor"
 )
 
 // stockLevel keeps track of stock levels across reloads.
@@ -19,72 +20,127 @@ type stockLevel struct {
 	product   product
 	inStock   bool
 	updatedAt time.Time
+	checkedAt time.Time
+	seenAt    time.Time    // Most recent time the product was seen in stock.
+	meta      metadata     // Product name, price etc. Empty until fetched.
+	prices    []pricePoint // Price history, oldest first. Only changes are recorded.
+	err       error        // Error from the most recent availability check, if any.
+	stores    []storeStock // Availability in each of MainLoop.Stores, as of the most recent successful check.
+	storeErr  error        // Error from the most recent store availability check, if any.
 }
 
 // MainLoop is the loop that has a dual purpose:
 // 1. Reload the products_sample.txt file when it changes
-// 2. Periodically check product availability
+// 2. Periodically check product availability, one product at a time, spread evenly across each product's interval
 type MainLoop struct {
-	ViewPort             *cursor.Area
+	ViewPort             View  // Usually a cursor.Area.
+	Clock                Clock // Drives the loop's tickers and timers. Nil means the system clock.
 	Country              Country
 	AvailabilityInterval time.Duration
-	RequestTimeout       time.Duration
-	Client               *http.Client
+	RequestBudget        int               // Maximum number of availability requests per minute, including in-store checks, zero means no limit.
+	HotInterval          time.Duration     // Interval between checks for products recently seen in stock, zero disables.
+	HotDuration          time.Duration     // How long a product stays "hot" after it was last seen in stock.
+	PriceChange          float64           // Minimum price change, in percent, that triggers a notification, zero disables.
+	CacheTTL             time.Duration     // How long API responses are reused before they're revalidated, zero disables. See CacheUntilDue.
+	APIBase              string            // Scheme and host of the API, eg. "http://localhost:8080". Empty means lvAPIBase.
+	Endpoints            map[string]string // Path templates that override the defaults by endpoint name, see ValidateEndpoints.
+	Client               *http.Client      // Shared by all requests, see NewClient. Must not be modified once the loop runs.
 	PFileName            string
 	PFileInterval        time.Duration
 	Notification         func(title, msg string)
 	OpenBrowser          bool
+	Browser              func(url string) // Opens product URL's when OpenBrowser is set. Nil means the default browser.
+	Watches              []string         // Category URL's or search queries to watch for new products.
+	WatchInterval        time.Duration    // Interval between polls of the watches.
+	AutoAdd              bool             // Append new products found by the watches to the P-file.
+	Stores               []string         // ID's of boutiques to check availability in, in addition to online.
+	Proxies              *ProxyPool       // Proxy pool used by Client, if any. Its stats are shown in the view.
+	HeaderProfiles       []HeaderProfile  // Requests rotate through these. If empty, the first of DefaultHeaderProfiles is used.
+	Cookies              *CookieJar       // Cookie jar used by Client, if any. Enables bootstrapping of sessions, see MainLoop.get.
+
+	headerCount  uint32        // Number of requests sent, used to rotate header profiles. Accessed atomically.
+	cache        responseCache // Availability responses. Safe for concurrent use, not protected by the lock below.
+	detailsCache responseCache // Product details responses. Safe for concurrent use, not protected by the lock below.
+	storeCache   responseCache // In-store availability responses. Safe for concurrent use, not protected by the lock below.
+	sessions     sessions      // Safe for concurrent use, not protected by the lock below.
 
-	sync.Mutex                       // Protects the field(s) below.
-	products   map[string]stockLevel // Key is product ID, value is stockLevel.
+	sync.Mutex                        // Protects the field(s) below.
+	products   map[string]stockLevel  // Key is product.key(), ie. product ID and SKU, value is stockLevel.
+	watches    map[string]*watchState // Key is an element of Watches.
 	message    string
 }
 
 // Run starts the main loop. Run does not exit until interrupted by a SIGINT, or if an unrecoverable error occurs.
 func (m *MainLoop) Run() error {
-	// Init stock levels.
-	m.products = make(map[string]stockLevel)
-
 	// Set up OS interrupts.
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	return m.run(sigs)
+}
 
-	// Set up P-file monitor.
+// run is Run, except that it exits when it receives from sigs, which allows tests to stop it.
+func (m *MainLoop) run(sigs <-chan os.Signal) error {
+	// The cookie jar and proxy pool follow the loop's clock, unless they were given one of their own.
+	if m.Cookies != nil && m.Cookies.Clock == nil {
+		m.Cookies.Clock = m.Clock
+	}
+	if m.Proxies != nil && m.Proxies.Clock == nil {
+		m.Proxies.Clock = m.Clock
+	}
+
+	// Init stock levels.
+	m.Lock()
+	m.products = make(map[string]stockLevel)
+	m.Unlock()
+
+	// Set up P-file monitor. Reloads may follow short links, and look up model codes and sizes, over the network, and
+	// take longer than PFileInterval, so they run one at a time; reloading is set while one runs, accessed atomically.
 	lastRead := time.Time{}
-	pFileTicker := time.NewTicker(m.PFileInterval)
+	var reloading int32
+	pFileTicker := m.clock().NewTicker(m.PFileInterval)
 	pFileFunc := func() {
 		if !m.PFileModifiedSince(lastRead) {
 			return
 		}
-		lastRead = time.Now()
+		lastRead = m.now()
 		ps, err := m.ReadPFile()
-		if err != nil {
+		var perr pFileError
+		if err != nil && !errors.As(err, &perr) {
 			m.output(err.Error())
 			return
 		}
+		ps, err = m.resolveIDs(ps)
+		perr = perr.append(err)
+		ps, err = m.resolveSizes(ps)
+		perr = perr.append(err)
 		if len(ps) == 0 {
-			m.output("No products to monitor, please update your products text file")
+			msg := "No products to monitor, please update your products text file"
+			if perr != nil {
+				msg += "\n" + perr.Error()
+			}
+			m.output(msg)
 			return
 		}
 		m.Lock()
 		{
 			// Update products.
-			m.message = ""
-			var pID string
+			var key string
 			var pCached stockLevel
 			var ok bool
+			skipped := 0
 			for _, p := range ps {
-				pID = p.productID()
-				if pID == "" {
-					m.output("Failed to determine product ID for one of the URL's, does it include a product code?")
+				key = p.key()
+				if key == "" {
+					skipped++
 					continue
 				}
-				pCached, ok = m.products[pID]
+				pCached, ok = m.products[key]
 				if ok {
+					pCached.product = p // Options may have changed.
 					pCached.updatedAt = lastRead
-					m.products[pID] = pCached
+					m.products[key] = pCached
 				} else {
-					m.products[pID] = stockLevel{
+					m.products[key] = stockLevel{
 						product:   p,
 						inStock:   false,
 						updatedAt: lastRead,
@@ -92,46 +148,108 @@ func (m *MainLoop) Run() error {
 				}
 			}
 			// Un-cache removed products (they will have updatedAt < lastRead).
-			for pID, pCached = range m.products {
+			for key, pCached = range m.products {
 				if pCached.updatedAt.Before(lastRead) {
-					delete(m.products, pID)
+					delete(m.products, key)
 				}
 			}
+			var msgs []string
+			if perr != nil {
+				msgs = append(msgs, perr.Error())
+			}
+			if skipped > 0 {
+				msgs = append(msgs, fmt.Sprintf("Skipped %d line(s) without a product ID, does each URL include a product code?", skipped))
+			}
+			if m.effectiveInterval() > m.AvailabilityInterval {
+				msgs = append(msgs, fmt.Sprintf("Warning: %d products exceed the request budget, each product is checked every %s", len(m.products), m.effectiveInterval()))
+			}
+			m.message = strings.Join(msgs, "\n")
 		}
 		m.Unlock()
 		m.updateView()
 	}
 
-	// Set up availability checks.
-	availTicker := time.NewTicker(m.AvailabilityInterval)
+	// Set up availability checks. Each tick checks a single product, so requests are spread out over time.
+	m.Lock()
+	availTimer := m.clock().NewTimer(m.spacing())
+	m.Unlock()
 	availFunc := func() {
-		m.Lock()
 		defer m.updateView()
-		defer m.Unlock()
 
-		m.message = ""
-		if len(m.products) == 0 {
+		m.Lock()
+		key, lvl, ok := m.next(m.now())
+		m.Unlock()
+		if !ok {
 			return
 		}
-		// Perform availability checks.
-		// TODO: We keep the lock during availability checks, this can be improved.
-		for pID, lvl := range m.products {
-			inStock, err := m.availability(lvl.product)
-			if err != nil {
-				m.message = fmt.Sprintf("Unable to check availability of %q: %s", pID, err.Error())
-				continue
+
+		// The lock is not held during the request, so the product may have been removed once we're done.
+		inStock, err := m.availability(lvl.product)
+		meta := lvl.meta
+		if err == nil {
+			// Metadata is nice to have, so errors are ignored. Details are cached, and so are failures to fetch them, so
+			// this rarely costs a request, which is why it isn't counted against the request budget.
+			if md, err := m.metadata(lvl.product); err == nil {
+				meta = md
 			}
-			if inStock && !lvl.inStock {
-				if m.Notification != nil {
-					m.Notification("Vuitton Monitor", fmt.Sprintf("Product %q is in stock!", pID))
-				}
-				if m.OpenBrowser {
-					m.browseTo(lvl.product.URL)
+		}
+		// Skip boutiques if the online check failed, as a rate limit or block would fail it too, and cost a request.
+		stores, storeErr := lvl.stores, lvl.storeErr
+		if err == nil {
+			stores, storeErr = m.storeAvailability(lvl.product)
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		cur, ok := m.products[key]
+		if !ok {
+			return
+		}
+		if kind := alertKind(err); kind != nil && !errors.Is(cur.err, kind) && m.Notification != nil {
+			m.Notification("Vuitton Monitor", errorMessage(key, meta, err))
+		}
+		cur.err = err
+		cur.meta = meta
+		cur.storeErr = storeErr
+		if storeErr == nil {
+			if m.Notification != nil {
+				for _, s := range storeArrivals(cur.stores, stores) {
+					m.Notification("Vuitton Monitor", inStoreMessage(key, cur.meta, s))
 				}
 			}
-			lvl.inStock = inStock
-			m.products[pID] = lvl
+			cur.stores = stores
+		}
+		if err != nil {
+			m.products[key] = cur
+			return
+		}
+		if prev, ok := cur.recordPrice(meta, m.now()); ok && m.Notification != nil {
+			name := meta.String()
+			for _, alert := range priceAlerts(cur.product, name, prev, cur.prices[len(cur.prices)-1], m.PriceChange) {
+				m.Notification("Vuitton Monitor", alert)
+			}
 		}
+		if inStock && !cur.inStock {
+			if m.Notification != nil {
+				m.Notification("Vuitton Monitor", inStockMessage(key, cur.meta))
+			}
+			if m.OpenBrowser {
+				m.browse(cur.product.URL)
+			}
+		}
+		if inStock {
+			cur.seenAt = m.now()
+		}
+		cur.inStock = inStock
+		m.products[key] = cur
+	}
+
+	// Set up watches. A nil channel blocks forever, so without watches, the select below never picks it.
+	var watchC <-chan time.Time
+	if len(m.Watches) > 0 {
+		watchTicker := m.clock().NewTicker(m.WatchInterval)
+		watchC = watchTicker.C()
+		go m.pollWatches()
 	}
 
 	// Load products once before entering the loop.
@@ -141,19 +259,76 @@ func (m *MainLoop) Run() error {
 	for {
 		select {
 		case <-sigs:
+			// Save cookies that were set since the last save, which is debounced, so the next run can reuse them.
+			msg := "Bye!"
+			if m.Cookies != nil {
+				if err := m.Cookies.saveChanged(m.now(), 0); err != nil {
+					msg = "Unable to save cookies: " + err.Error() + "\n" + msg
+				}
+			}
 			m.Lock()
-			m.message = "Bye!"
+			m.message = msg
 			m.Unlock()
 			m.updateView()
 			return nil // TODO(mkock) Proper shutdown!
-		case <-availTicker.C:
+		case <-availTimer.C():
 			go availFunc()
-		case <-pFileTicker.C:
-			go pFileFunc()
+			m.Lock()
+			availTimer.Reset(m.spacing())
+			m.Unlock()
+		case <-pFileTicker.C():
+			// Skip the tick if the previous reload hasn't finished. The next tick picks up any changes made meanwhile.
+			if atomic.CompareAndSwapInt32(&reloading, 0, 1) {
+				go func() {
+					defer atomic.StoreInt32(&reloading, 0)
+					pFileFunc()
+				}()
+			}
+		case <-watchC:
+			go func() {
+				m.pollWatches()
+				m.updateView()
+			}()
 		}
 	}
 }
 
+// inStockMessage returns the notification message for a product that came in stock.
+func inStockMessage(key string, md metadata) string {
+	if md.Name == "" {
+		return fmt.Sprintf("Product %q is in stock!", key)
+	}
+	msg := fmt.Sprintf("%s is in stock!", md.String())
+	if p := md.formatPrice(); p != "" {
+		msg += " Price: " + p
+	}
+	if md.ImageURL != "" {
+		msg += "\n" + md.ImageURL
+	}
+	return msg
+}
+
+// errorMessage returns the notification message for a product that can't be checked.
+func errorMessage(key string, md metadata, err error) string {
+	name := fmt.Sprintf("product %q", key)
+	if md.Name != "" {
+		name = md.String()
+	}
+	if errors.Is(err, errNotFound) {
+		return fmt.Sprintf("Unable to check %s, it may have been removed from the catalog: %s", name, err.Error())
+	}
+	return fmt.Sprintf("Unable to check %s: %s", name, err.Error())
+}
+
+// browse opens the given url with MainLoop.Browser, or in the default browser if it isn't set.
+func (m *MainLoop) browse(url string) {
+	if m.Browser != nil {
+		m.Browser(url)
+		return
+	}
+	m.browseTo(url)
+}
+
 // browseTo opens a browser with the given url.
 // If opening of the browser fails, then calling browseTo is a no-op.
 func (m *MainLoop) browseTo(url string) {
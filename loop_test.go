@@ -0,0 +1,594 @@
+package vuitton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"vuitton/fakelv"
+)
+
+// fakeClock is a Clock that only moves when advanced. Its timers and tickers fire during Advance.
+type fakeClock struct {
+	sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a timer or ticker of a fakeClock.
+type fakeTimer struct {
+	clock  *fakeClock
+	c      chan time.Time
+	at     time.Time     // When the timer fires next.
+	period time.Duration // Zero for timers, which fire once.
+	armed  bool
+}
+
+// fakeTicker is a fakeTimer with the Stop method of a Ticker.
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTicker(d time.Duration) Ticker {
+	return fakeTicker{c.add(d, d)}
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	return c.add(d, 0)
+}
+
+func (c *fakeClock) add(d, period time.Duration) *fakeTimer {
+	c.Lock()
+	defer c.Unlock()
+	tm := &fakeTimer{clock: c, c: make(chan time.Time, 1), at: c.now.Add(d), period: period, armed: true}
+	c.timers = append(c.timers, tm)
+	return tm
+}
+
+// Advance moves the clock forward by d, firing the timers and tickers that are due on the way, in order. Like package
+// time, a tick is dropped if the previous one hasn't been received yet.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	end := c.now.Add(d)
+	for {
+		var next *fakeTimer
+		for _, tm := range c.timers {
+			if tm.armed && !tm.at.After(end) && (next == nil || tm.at.Before(next.at)) {
+				next = tm
+			}
+		}
+		if next == nil {
+			break
+		}
+		c.now = next.at
+		select {
+		case next.c <- c.now:
+		default:
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			next.armed = false
+		}
+	}
+	c.now = end
+}
+
+// armed returns the number of timers and tickers that will fire when the clock is advanced far enough.
+func (c *fakeClock) armed() int {
+	c.Lock()
+	defer c.Unlock()
+	n := 0
+	for _, tm := range c.timers {
+		if tm.armed {
+			n++
+		}
+	}
+	return n
+}
+
+func (tm *fakeTimer) C() <-chan time.Time {
+	return tm.c
+}
+
+func (tm *fakeTimer) Reset(d time.Duration) bool {
+	tm.clock.Lock()
+	defer tm.clock.Unlock()
+	armed := tm.armed
+	tm.at = tm.clock.now.Add(d)
+	tm.armed = true
+	return armed
+}
+
+func (tm *fakeTimer) Stop() bool {
+	tm.clock.Lock()
+	defer tm.clock.Unlock()
+	armed := tm.armed
+	tm.armed = false
+	return armed
+}
+
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+// fakeView is a View that keeps the most recent content, and counts updates.
+type fakeView struct {
+	sync.Mutex
+	content string
+	updates int
+}
+
+func (v *fakeView) Clear() {}
+
+func (v *fakeView) Update(content string) {
+	v.Lock()
+	defer v.Unlock()
+	v.content = content
+	v.updates++
+}
+
+func (v *fakeView) state() (string, int) {
+	v.Lock()
+	defer v.Unlock()
+	return v.content, v.updates
+}
+
+// loopTest runs a MainLoop against a fake API, with a fake clock and view, and records its notifications and browser
+// launches.
+type loopTest struct {
+	t     *testing.T
+	m     *MainLoop
+	api   *fakelv.Server
+	clock *fakeClock
+	view  *fakeView
+	sigs  chan os.Signal
+	done  chan error
+
+	sync.Mutex
+	notifications []string
+	browsed       []string
+}
+
+// startLoop writes the given lines to a P-file, and runs a MainLoop that monitors it. If configure isn't nil, it may
+// change the loop before it runs. The loop is stopped when the test ends.
+func startLoop(t *testing.T, api *fakelv.Server, availabilityInterval, pFileInterval time.Duration, configure func(m *MainLoop), lines ...string) *loopTest {
+	srv := api.Start()
+	t.Cleanup(srv.Close)
+	lt := &loopTest{
+		t:     t,
+		api:   api,
+		clock: newFakeClock(),
+		view:  &fakeView{},
+		sigs:  make(chan os.Signal, 1),
+		done:  make(chan error, 1),
+	}
+	lt.m = &MainLoop{
+		ViewPort:             lt.view,
+		Clock:                lt.clock,
+		Country:              "DK",
+		AvailabilityInterval: availabilityInterval,
+		CacheTTL:             CacheUntilDue,
+		PFileName:            filepath.Join(t.TempDir(), "products.txt"),
+		PFileInterval:        pFileInterval,
+		APIBase:              srv.URL,
+		Client:               &http.Client{Timeout: time.Second},
+		OpenBrowser:          true,
+		Notification: func(title, msg string) {
+			lt.Lock()
+			defer lt.Unlock()
+			lt.notifications = append(lt.notifications, msg)
+		},
+		Browser: func(url string) {
+			lt.Lock()
+			defer lt.Unlock()
+			lt.browsed = append(lt.browsed, url)
+		},
+	}
+	if configure != nil {
+		configure(lt.m)
+	}
+	lt.writePFile(lines...)
+
+	go func() { lt.done <- lt.m.run(lt.sigs) }()
+	t.Cleanup(func() {
+		select {
+		case lt.sigs <- syscall.SIGINT:
+		default:
+		}
+		<-lt.done
+	})
+	lt.waitFor("initial P-file read", func() bool {
+		_, updates := lt.view.state()
+		return updates > 0
+	})
+	return lt
+}
+
+// writePFile replaces the P-file, with a modification time just after the fake clock's current time.
+func (lt *loopTest) writePFile(lines ...string) {
+	lt.t.Helper()
+	if err := ioutil.WriteFile(lt.m.PFileName, []byte(strings.Join(lines, "\n")), 0600); err != nil {
+		lt.t.Fatal(err)
+	}
+	mtime := lt.clock.Now().Add(time.Second)
+	if err := os.Chtimes(lt.m.PFileName, mtime, mtime); err != nil {
+		lt.t.Fatal(err)
+	}
+}
+
+// advance waits for the loop to arm its timers, moves the fake clock forward by d, and waits for the loop to update
+// the view, which it does once it has handled the tick.
+func (lt *loopTest) advance(d time.Duration) {
+	lt.t.Helper()
+	lt.waitFor("timers", func() bool { return lt.clock.armed() == 2 })
+	_, before := lt.view.state()
+	lt.clock.Advance(d)
+	lt.waitFor("view update", func() bool {
+		_, updates := lt.view.state()
+		return updates > before
+	})
+}
+
+// waitFor waits until cond returns true, and fails the test if it takes too long.
+func (lt *loopTest) waitFor(what string, cond func() bool) {
+	lt.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			lt.t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// recorded returns the notifications and browser launches since the previous call.
+func (lt *loopTest) recorded() ([]string, []string) {
+	lt.Lock()
+	defer lt.Unlock()
+	notifications, browsed := lt.notifications, lt.browsed
+	lt.notifications, lt.browsed = nil, nil
+	return notifications, browsed
+}
+
+// stockLevel returns the stock level of the product with the given key.
+func (lt *loopTest) stockLevel(key string) (stockLevel, bool) {
+	lt.m.Lock()
+	defer lt.m.Unlock()
+	lvl, ok := lt.m.products[key]
+	return lvl, ok
+}
+
+func TestMainLoopAvailability(t *testing.T) {
+	const (
+		key = "nvprod3130266v#1A9JNC@eng-nl"
+		url = "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JNC"
+	)
+	api := fakelv.New()
+	api.AddProduct("nvprod3130266v", "1A9JN8", "1A9JNC")
+	lt := startLoop(t, api, 30*time.Second, time.Hour, nil, url)
+
+	tests := []struct {
+		change       func()
+		inStock      bool
+		label        string // Shown in the "In stock?" column.
+		notification string // Beginning of the notification, if any.
+		browsed      bool
+	}{
+		{func() {}, false, "No", "", false},
+		{func() { api.SetStock("nvprod3130266v", "1A9JN8", true) }, false, "No", "", false},
+		{func() { api.SetStock("nvprod3130266v", "1A9JNC", true) }, true, "Yes", `Product "nvprod3130266v#1A9JNC@eng-nl" is in stock!`, true},
+		{func() {}, true, "Yes", "", false},
+		{func() { api.SetStock("nvprod3130266v", "1A9JNC", false) }, false, "No", "", false},
+		{func() { api.RateLimit(1) }, false, "Rate limited", "", false},
+		{func() { api.Malform(1) }, false, "Blocked", `Unable to check product "nvprod3130266v#1A9JNC@eng-nl"`, false},
+		{func() { api.SetStock("nvprod3130266v", "1A9JNC", true) }, true, "Yes", `Product "nvprod3130266v#1A9JNC@eng-nl" is in stock!`, true},
+	}
+	for i, test := range tests {
+		test.change()
+		lt.advance(30 * time.Second)
+
+		lvl, ok := lt.stockLevel(key)
+		if !ok || lvl.inStock != test.inStock {
+			t.Errorf("expected in stock %v for step %d, got %v", test.inStock, i+1, lvl.inStock)
+		}
+		if !lvl.checkedAt.Equal(lt.clock.Now()) {
+			t.Errorf("expected check at %s for step %d, got %s", lt.clock.Now(), i+1, lvl.checkedAt)
+		}
+		if content, _ := lt.view.state(); !strings.Contains(content, test.label) {
+			t.Errorf("expected %q in view for step %d, got:\n%s", test.label, i+1, content)
+		}
+		notifications, browsed := lt.recorded()
+		switch {
+		case test.notification == "" && len(notifications) > 0:
+			t.Errorf("expected no notifications for step %d, got %q", i+1, notifications)
+		case test.notification != "" && (len(notifications) != 1 || !strings.HasPrefix(notifications[0], test.notification)):
+			t.Errorf("expected notification %q for step %d, got %q", test.notification, i+1, notifications)
+		}
+		if test.browsed != (len(browsed) == 1 && browsed[0] == url) || len(browsed) > 1 {
+			t.Errorf("expected browser launch %v for step %d, got %q", test.browsed, i+1, browsed)
+		}
+	}
+	if api.Requests() != len(tests) {
+		t.Errorf("expected %d requests, got %d", len(tests), api.Requests())
+	}
+}
+
+func TestMainLoopSharedProductID(t *testing.T) {
+	api := fakelv.New()
+	api.AddProduct("nvprod3130266v", "1A9JN8", "1A9JNC")
+	api.SetStock("nvprod3130266v", "1A9JNC", true)
+	lt := startLoop(t, api, 30*time.Second, time.Hour, nil, "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8,1A9JNC")
+
+	// The first check is due after the interval, then the two SKU's take turns every 15 seconds. Each response is
+	// shared with the other SKU, which is checked before the one that fetched it is due again.
+	lt.advance(30 * time.Second)
+	for i := 0; i < 5; i++ {
+		lt.advance(15 * time.Second)
+	}
+	if api.Requests() != 3 {
+		t.Errorf("expected 3 requests for 6 checks, got %d", api.Requests())
+	}
+	for key, expected := range map[string]bool{"nvprod3130266v#1A9JN8@eng-nl": false, "nvprod3130266v#1A9JNC@eng-nl": true} {
+		if lvl, _ := lt.stockLevel(key); lvl.inStock != expected {
+			t.Errorf("expected in stock %v for %s, got %v", expected, key, lvl.inStock)
+		}
+	}
+}
+
+// countingTransport counts the requests whose path contains a substring.
+type countingTransport struct {
+	sync.Mutex
+	substr string
+	count  int
+}
+
+func (ct *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ct.Lock()
+	if strings.Contains(req.URL.Path, ct.substr) {
+		ct.count++
+	}
+	ct.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (ct *countingTransport) requests() int {
+	ct.Lock()
+	defer ct.Unlock()
+	return ct.count
+}
+
+func TestMainLoopStores(t *testing.T) {
+	const key = "nvprod3130266v#1A9JNC@eng-nl"
+	api := fakelv.New()
+	api.AddProduct("nvprod3130266v", "1A9JN8", "1A9JNC")
+	stores := &countingTransport{substr: "/stores"}
+	lt := startLoop(t, api, 30*time.Second, time.Hour, func(m *MainLoop) {
+		m.Stores = []string{"A29"}
+		m.Client.Transport = stores
+	}, "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JNC")
+
+	tests := []struct {
+		change   func()
+		requests int
+		storeErr bool
+	}{
+		// The fake API has no boutiques, so store checks fail.
+		{func() {}, 1, true},
+		// Store checks are skipped while online checks fail.
+		{func() { api.RateLimit(1) }, 1, true},
+		{func() {}, 2, true},
+	}
+	for i, test := range tests {
+		test.change()
+		lt.advance(30 * time.Second)
+		if stores.requests() != test.requests {
+			t.Errorf("expected %d store requests for step %d, got %d", test.requests, i+1, stores.requests())
+		}
+		if lvl, _ := lt.stockLevel(key); (lvl.storeErr != nil) != test.storeErr {
+			t.Errorf("expected store error %v for step %d, got %v", test.storeErr, i+1, lvl.storeErr)
+		}
+	}
+}
+
+func TestMainLoopPFile(t *testing.T) {
+	const (
+		charlie = "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JNC"
+		pocket  = "https://en.louisvuitton.com/eng-nl/products/pocket-organiser-damier-graphite-nvprod3430052v"
+	)
+	api := fakelv.New()
+	lt := startLoop(t, api, time.Hour, 10*time.Second, nil, charlie)
+
+	tests := []struct {
+		lines    []string
+		expected []string
+		interval time.Duration // Of the pocket organiser, if monitored.
+		messages []string
+	}{
+		{[]string{charlie, pocket}, []string{"nvprod3130266v#1A9JNC@eng-nl", "nvprod3430052v@eng-nl"}, 0, nil},
+		{[]string{pocket + " interval=2m priority=high"}, []string{"nvprod3430052v@eng-nl"}, 2 * time.Minute, nil},
+		{[]string{pocket, "https://en.louisvuitton.com/eng-nl/products/no-product-id"}, []string{"nvprod3430052v@eng-nl"}, 0, []string{"Skipped 1 line(s)"}},
+		// All problems are shown, not just the last one.
+		{[]string{pocket, "https://en.louisvuitton.com/eng-nl/products/no-product-id", charlie + " priority=urgent"}, []string{"nvprod3430052v@eng-nl"}, 0, []string{"Skipped 1 line(s)", "skipped line 3 of the product file: invalid priority"}},
+		// Products are kept if no line can be parsed, and the reason is shown.
+		{[]string{charlie + " priority=urgent"}, []string{"nvprod3430052v@eng-nl"}, 0, []string{"No products to monitor", "skipped line 1 of the product file: invalid priority"}},
+		{[]string{}, []string{"nvprod3430052v@eng-nl"}, 0, []string{"No products to monitor"}},
+	}
+	for i, test := range tests {
+		lt.writePFile(test.lines...)
+		lt.advance(10 * time.Second)
+
+		lt.m.Lock()
+		keys := make([]string, 0, len(lt.m.products))
+		for key := range lt.m.products {
+			keys = append(keys, key)
+		}
+		lt.m.Unlock()
+		if len(keys) != len(test.expected) {
+			t.Errorf("expected products %q for step %d, got %q", test.expected, i+1, keys)
+		}
+		content, _ := lt.view.state()
+		for _, key := range test.expected {
+			if _, ok := lt.stockLevel(key); !ok {
+				t.Errorf("expected product %s for step %d, got %q", key, i+1, keys)
+			}
+			pID := strings.FieldsFunc(key, func(r rune) bool { return r == '#' || r == '@' })[0]
+			if len(test.messages) == 0 && !strings.Contains(content, pID) {
+				t.Errorf("expected %s in view for step %d, got:\n%s", pID, i+1, content)
+			}
+		}
+		if lvl, _ := lt.stockLevel("nvprod3430052v@eng-nl"); lvl.product.Interval != test.interval {
+			t.Errorf("expected interval %s for step %d, got %s", test.interval, i+1, lvl.product.Interval)
+		}
+		for _, msg := range test.messages {
+			if !strings.Contains(content, msg) {
+				t.Errorf("expected %q in view for step %d, got:\n%s", msg, i+1, content)
+			}
+		}
+	}
+
+	if api.Requests() != 0 {
+		t.Errorf("expected no availability checks, got %d", api.Requests())
+	}
+}
+
+// blockingTransport holds requests whose path contains a substring until it's released, and then responds with 404 Not
+// Found. It records the paths of the requests, and the highest number of them held at once.
+type blockingTransport struct {
+	substr  string
+	release chan struct{}
+
+	sync.Mutex
+	paths   []string
+	held    int
+	maxHeld int
+}
+
+func (bt *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.Contains(req.URL.Path, bt.substr) {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	bt.Lock()
+	bt.paths = append(bt.paths, req.URL.Path)
+	bt.held++
+	if bt.held > bt.maxHeld {
+		bt.maxHeld = bt.held
+	}
+	bt.Unlock()
+	<-bt.release
+	bt.Lock()
+	bt.held--
+	bt.Unlock()
+	return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func (bt *blockingTransport) state() ([]string, int) {
+	bt.Lock()
+	defer bt.Unlock()
+	return append([]string(nil), bt.paths...), bt.maxHeld
+}
+
+func TestMainLoopSlowPFile(t *testing.T) {
+	tests := []struct {
+		name         string
+		substr       string
+		first, again string // Lines of the P-file for the first reload, and the one attempted while it's in progress.
+	}{
+		{"sizes", "/catalog/product/", "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v size=8", "https://en.louisvuitton.com/eng-nl/products/pocket-organiser-damier-graphite-nvprod3430052v size=9"},
+		{"model codes", "/catalog/sku/", "M40995", "M40996"},
+		{"short links", "/s/", "https://en.louisvuitton.com/s/charlie", "https://en.louisvuitton.com/s/pocket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bt := &blockingTransport{substr: tt.substr, release: make(chan struct{})}
+			lt := startLoop(t, fakelv.New(), time.Hour, 10*time.Second, func(m *MainLoop) {
+				m.Client.Transport = bt
+			}, "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JNC")
+
+			// The first reload blocks on its request, so the next tick comes while it's in progress.
+			lt.writePFile(tt.first)
+			lt.waitFor("timers", func() bool { return lt.clock.armed() == 2 })
+			lt.clock.Advance(10 * time.Second)
+			lt.waitFor("first request", func() bool {
+				paths, _ := bt.state()
+				return len(paths) == 1
+			})
+			lt.writePFile(tt.again)
+			lt.clock.Advance(10 * time.Second)
+			time.Sleep(50 * time.Millisecond) // Gives an overlapping reload the chance to send its request.
+			if paths, _ := bt.state(); len(paths) != 1 {
+				t.Errorf("expected 1 request while the first reload is in progress, got %q", paths)
+			}
+
+			// Once the first reload is done, the next tick picks up the change it skipped.
+			_, before := lt.view.state()
+			close(bt.release)
+			lt.waitFor("first reload", func() bool {
+				_, updates := lt.view.state()
+				return updates > before
+			})
+			lt.advance(10 * time.Second)
+			paths, maxHeld := bt.state()
+			if len(paths) != 2 || maxHeld != 1 {
+				t.Errorf("expected 2 requests, one at a time, got %q with up to %d at once", paths, maxHeld)
+			}
+		})
+	}
+}
+
+func TestMainLoopStop(t *testing.T) {
+	// Cookies that are pending a save are saved on the way out.
+	filename := filepath.Join(t.TempDir(), "cookies.json")
+	jar, err := NewCookieJar(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &url.URL{Scheme: "https", Host: "api.louisvuitton.com", Path: "/"}
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "v1"}})
+	configure := func(m *MainLoop) {
+		m.Cookies = jar
+	}
+	lt := startLoop(t, fakelv.New(), time.Minute, time.Minute, configure, "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v")
+	lt.sigs <- syscall.SIGINT
+	select {
+	case err := <-lt.done:
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the loop to stop")
+	}
+	lt.done <- nil // For the cleanup.
+	if jar.Clock != lt.clock {
+		t.Errorf("expected the cookie jar to use the loop's clock")
+	}
+	if content, _ := lt.view.state(); !strings.Contains(content, "Bye!") {
+		t.Errorf("expected goodbye in view, got:\n%s", content)
+	}
+	saved, err := NewCookieJar(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(saved.Cookies(u)); got != "[session=v1]" {
+		t.Errorf("expected [session=v1], got %s", got)
+	}
+}
@@ -2,16 +2,42 @@ package vuitton
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// psMax is the maximum number of products that we are willing to track.
-const psMax = 15
+// lineError describes a single line in the P-file that could not be parsed.
+type lineError struct {
+	line int
+	err  error
+}
+
+// pFileError is returned by ReadPFile, alongside the products that could be parsed, when some lines could not be parsed.
+type pFileError []lineError
 
-var errMaxExceeded = errors.New("too many products being tracked")
+func (e pFileError) Error() string {
+	if len(e) == 1 {
+		return fmt.Sprintf("skipped line %d of the product file: %s", e[0].line, e[0].err.Error())
+	}
+	lines := make([]string, 0, len(e))
+	for _, le := range e {
+		lines = append(lines, fmt.Sprint(le.line))
+	}
+	return fmt.Sprintf("skipped lines %s of the product file, first error: %s", strings.Join(lines, ", "), e[0].err.Error())
+}
+
+// append appends the lines from err to e, if err is a pFileError. Other errors are ignored.
+func (e pFileError) append(err error) pFileError {
+	var other pFileError
+	if errors.As(err, &other) {
+		return append(e, other...)
+	}
+	return e
+}
 
 // PFileModifiedSince returns true if the PFile has been modified since the given time.
 func (m *MainLoop) PFileModifiedSince(when time.Time) bool {
@@ -24,7 +50,9 @@ func (m *MainLoop) PFileModifiedSince(when time.Time) bool {
 
 // ReadPFile reads the "P" file (products) file from disk and converts the URL's into a slice of products.
 // ReadPFile returns an error if it was unable to read the file. If the file was empty, ReadPFile just returns an empty
-// slice and a nil error.
+// slice and a nil error. There is no upper limit on the number of products; the scheduler spreads out the checks.
+// Lines that can't be parsed are skipped, and reported via a pFileError that is returned along with the other products.
+// Lines listing several SKU's, separated by commas, result in one product per SKU.
 func (m *MainLoop) ReadPFile() ([]product, error) {
 	if m.PFileName == "" {
 		return []product{}, nil
@@ -42,17 +70,98 @@ func (m *MainLoop) ReadPFile() ([]product, error) {
 	lines := strings.Split(string(bytes), "\n")
 
 	ps := make([]product, 0, len(lines))
-	for _, l := range lines {
+	var perr pFileError
+	for i, l := range lines {
 		l = strings.TrimSpace(l)
 		if l == "" {
 			continue
 		}
-		ps = append(ps, product{URL: l})
+		p, err := parseLine(l, m.Country)
+		if err != nil {
+			perr = append(perr, lineError{line: i + 1, err: err})
+			continue
+		}
+		p.line = i + 1
+		ps = append(ps, p.expand()...)
 	}
 
-	if len(ps) > psMax {
-		return []product{}, errMaxExceeded
+	if len(perr) > 0 {
+		return ps, perr
 	}
-
 	return ps, nil
 }
+
+// parseLine converts a single line from the P-file into a product.
+// A line consists of a product URL, optionally followed by whitespace-separated options in the form key=value.
+// Instead of a URL, a bare product ID or model code may be given, optionally followed by "#" and SKU's. A URL is then
+// made up for the country given by the country option, or the country c if there is no such option. Options are:
+//
+//	interval=1m       check this product every minute rather than using the global interval
+//	priority=high     one of low, normal or high; when the request budget is exhausted, high priority goes first
+//	size=8,9          sizes to monitor, which are resolved to SKU's; an alternative to listing SKU's after a "#"
+//	below=1500        notify when the price drops to or below 1500
+//	above=2000        notify when the price rises to or above 2000
+//	country=jp        check availability in Japan, rather than the country found in the URL
+func parseLine(l string, c Country) (product, error) {
+	fields := strings.Fields(l)
+	if len(fields) == 0 {
+		return product{}, errors.New("empty line")
+	}
+	if len(fields) > 1 && strings.HasSuffix(fields[0], "#") {
+		// Tolerate whitespace between the hash symbol and the SKU.
+		fields = append([]string{fields[0] + fields[1]}, fields[2:]...)
+	}
+	p := product{URL: fields[0]}
+	_, bare := bareURL(fields[0], c)
+	if err := p.validate(); err != nil && !isShortLink(p.URL) && !bare {
+		return product{}, err
+	}
+	for _, opt := range fields[1:] {
+		parts := strings.SplitN(opt, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return product{}, fmt.Errorf("option %q must be in the form key=value", opt)
+		}
+		key, val := strings.ToLower(parts[0]), parts[1]
+		switch key {
+		case "interval":
+			d, err := time.ParseDuration(val)
+			if err != nil || d < time.Second {
+				return product{}, fmt.Errorf("invalid interval %q, must be a duration of at least 1s", val)
+			}
+			p.Interval = d
+		case "priority":
+			prio, ok := parsePriority(val)
+			if !ok {
+				return product{}, fmt.Errorf("invalid priority %q, must be one of low, normal or high", val)
+			}
+			p.Priority = prio
+		case "size":
+			p.Sizes = val
+		case "country":
+			c := Country(strings.ToUpper(val))
+			if !c.Valid() {
+				return product{}, fmt.Errorf("invalid country %q", val)
+			}
+			p.Country = c
+		case "below", "above":
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil || f <= 0 {
+				return product{}, fmt.Errorf("invalid price threshold %q, must be a positive number", val)
+			}
+			if key == "below" {
+				p.Below = f
+			} else {
+				p.Above = f
+			}
+		default:
+			return product{}, fmt.Errorf("unknown option %q", key)
+		}
+	}
+	if bare {
+		if p.Country != "" {
+			c = p.Country
+		}
+		p.URL, _ = bareURL(fields[0], c)
+	}
+	return p, nil
+}
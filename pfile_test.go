@@ -0,0 +1,104 @@
+package vuitton
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		in  string
+		out product
+		err bool
+	}{
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", product{URL: "https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v"}, false},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#	1A9JNC", product{URL: "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JNC"}, false},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v interval=5m", product{URL: "https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", Interval: 5 * time.Minute}, false},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v priority=HIGH interval=10s", product{URL: "https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", Interval: 10 * time.Second, Priority: priorityHigh}, false},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v size=8,9", product{URL: "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v", Sizes: "8,9"}, false},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v below=1500 above=2000.50", product{URL: "https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", Below: 1500, Above: 2000.5}, false},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v below=cheap", product{}, true},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v priority=urgent", product{}, true},
+		{"nvprod3130266v", product{URL: "https://en.louisvuitton.com/eng-nl/products/nvprod3130266v"}, false},
+		{"NVPROD3130266V#1A9JN8,1A9JNC", product{URL: "https://en.louisvuitton.com/eng-nl/products/nvprod3130266v#1A9JN8,1A9JNC"}, false},
+		{"nvprod3130266v country=jp", product{URL: "https://jp.louisvuitton.com/jpn-jp/products/nvprod3130266v", Country: "JP"}, false},
+		{"m40995 priority=high", product{URL: "https://en.louisvuitton.com/eng-nl/products/M40995", Priority: priorityHigh}, false},
+		{"1A9JN8", product{URL: "https://en.louisvuitton.com/eng-nl/products/1A9JN8"}, false},
+		{"005656", product{}, true},
+		{" \t ", product{}, true},
+		{"nvprod", product{}, true},
+		{"hello", product{}, true},
+		{"https://evil.com/louisvuitton/products/loop-bag-monogram-nvprod3190103v", product{}, true},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v interval=10ms", product{}, true},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v color=red", product{}, true},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v high", product{}, true},
+	}
+
+	for _, tt := range tests {
+		actual, err := parseLine(tt.in, "DK")
+		if (err != nil) != tt.err {
+			t.Errorf("%s: expected error %t, got %v", tt.in, tt.err, err)
+			continue
+		}
+		if actual != tt.out {
+			t.Errorf("%s: expected %+v, got %+v", tt.in, tt.out, actual)
+		}
+	}
+}
+
+func FuzzParseLine(f *testing.F) {
+	for _, l := range sampleLines(f) {
+		f.Add(l)
+		f.Add(l + " interval=1m priority=high size=8,9 below=1500 above=2000 country=jp")
+	}
+	f.Add("nvprod3130266v# 1A9JN8")
+	f.Add("https://en.louisvuitton.com/eng-nl/products/nvprod3130266v interval= =x")
+	f.Fuzz(func(t *testing.T, l string) {
+		p, err := parseLine(l, "DK")
+		if err != nil {
+			return
+		}
+		if p.URL == "" || p.Interval < 0 || p.Below < 0 || p.Above < 0 {
+			t.Errorf("unexpected product %+v for %q", p, l)
+		}
+		if strings.Contains(p.SKU(), "#") {
+			t.Errorf("unexpected SKU %q for %q", p.SKU(), l)
+		}
+	})
+}
+
+func FuzzReadPFile(f *testing.F) {
+	// Large seeds make minimization of interesting inputs slow, so lines are added in pairs rather than all at once.
+	lines := sampleLines(f)
+	for i := 1; i < len(lines); i++ {
+		f.Add(lines[i-1] + "\n" + lines[i])
+	}
+	f.Add(lines[0] + " interval=1m\r\n\r\n" + lines[1] + " size=8,9")
+	m := MainLoop{Country: "DK", PFileName: filepath.Join(f.TempDir(), "products.txt")}
+	f.Fuzz(func(t *testing.T, content string) {
+		if err := ioutil.WriteFile(m.PFileName, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		ps, err := m.ReadPFile()
+		var perr pFileError
+		if err != nil && !errors.As(err, &perr) {
+			t.Fatalf("unexpected error for %q: %s", content, err)
+		}
+		n := strings.Count(content, "\n") + 1
+		if len(perr) > n {
+			t.Errorf("expected at most %d line errors for %q, got %d", n, content, len(perr))
+		}
+		for _, p := range ps {
+			if p.line < 1 || p.line > n {
+				t.Errorf("unexpected line %d for %q", p.line, content)
+			}
+			if len(p.SKUs()) > 1 || strings.Contains(p.SKU(), "#") {
+				t.Errorf("expected at most one valid SKU per product, got %q for %q", p.SKUs(), content)
+			}
+		}
+	})
+}
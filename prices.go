@@ -0,0 +1,89 @@
+package vuitton
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// priceHistoryMax is the maximum number of price changes kept per product.
+const priceHistoryMax = 50
+
+// pricePoint is the price of a product at a point in time.
+type pricePoint struct {
+	at       time.Time
+	price    float64
+	currency string
+}
+
+// recordPrice appends the price from md to the product's price history, unless the price is unknown or hasn't changed.
+// recordPrice returns the previous price point, and false if there was no previous price.
+func (lvl *stockLevel) recordPrice(md metadata, now time.Time) (pricePoint, bool) {
+	if md.Price == 0 {
+		return pricePoint{}, false
+	}
+	var prev pricePoint
+	hasPrev := len(lvl.prices) > 0
+	if hasPrev {
+		prev = lvl.prices[len(lvl.prices)-1]
+		if prev.price == md.Price && prev.currency == md.Currency {
+			return prev, true
+		}
+	}
+	lvl.prices = append(lvl.prices, pricePoint{at: now, price: md.Price, currency: md.Currency})
+	if len(lvl.prices) > priceHistoryMax {
+		lvl.prices = lvl.prices[len(lvl.prices)-priceHistoryMax:]
+	}
+	return prev, hasPrev
+}
+
+// priceChange returns the relative change, in percent, from the first recorded price to the most recent one.
+// priceChange returns false if fewer than two prices in the same currency have been recorded.
+func (lvl stockLevel) priceChange() (float64, bool) {
+	if len(lvl.prices) < 2 {
+		return 0, false
+	}
+	first, last := lvl.prices[0], lvl.prices[len(lvl.prices)-1]
+	if first.currency != last.currency {
+		return 0, false
+	}
+	return percent(first.price, last.price), true
+}
+
+// formatPrice returns the current price, followed by the change since the first recorded price, if any.
+func (lvl stockLevel) formatPrice() string {
+	if pct, ok := lvl.priceChange(); ok {
+		return fmt.Sprintf("%s (%+.1f%%)", lvl.meta.formatPrice(), pct)
+	}
+	return lvl.meta.formatPrice()
+}
+
+// percent returns the relative change from a to b, in percent.
+func percent(a, b float64) float64 {
+	return (b - a) / a * 100
+}
+
+// priceAlerts returns a message for each alert triggered by the price changing from prev to cur:
+// when the price drops to or below the product's Below threshold, rises to or above its Above threshold, or changes
+// by at least changePct percent. A changePct of zero disables the latter. Prices in different currencies are not
+// compared.
+func priceAlerts(p product, name string, prev, cur pricePoint, changePct float64) []string {
+	if prev.price == 0 || cur.price == 0 || prev.currency != cur.currency || prev.price == cur.price {
+		return []string{}
+	}
+	format := func(pp pricePoint) string {
+		return metadata{Price: pp.price, Currency: pp.currency}.formatPrice()
+	}
+
+	alerts := make([]string, 0, 3)
+	if p.Below > 0 && prev.price > p.Below && cur.price <= p.Below {
+		alerts = append(alerts, fmt.Sprintf("%s dropped to %s, below your threshold of %.2f", name, format(cur), p.Below))
+	}
+	if p.Above > 0 && prev.price < p.Above && cur.price >= p.Above {
+		alerts = append(alerts, fmt.Sprintf("%s rose to %s, above your threshold of %.2f", name, format(cur), p.Above))
+	}
+	if pct := percent(prev.price, cur.price); changePct > 0 && math.Abs(pct) >= changePct {
+		alerts = append(alerts, fmt.Sprintf("%s changed price from %s to %s (%+.1f%%)", name, format(prev), format(cur), pct))
+	}
+	return alerts
+}
@@ -0,0 +1,70 @@
+package vuitton
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordPrice(t *testing.T) {
+	now := time.Now()
+	lvl := stockLevel{}
+
+	if _, ok := lvl.recordPrice(metadata{}, now); ok || len(lvl.prices) != 0 {
+		t.Error("expected unknown price to be ignored")
+	}
+	if _, ok := lvl.recordPrice(metadata{Price: 1000, Currency: "EUR"}, now); ok {
+		t.Error("expected no previous price")
+	}
+	if prev, ok := lvl.recordPrice(metadata{Price: 1000, Currency: "EUR"}, now.Add(time.Hour)); !ok || prev.price != 1000 {
+		t.Errorf("expected previous price 1000, got %v", prev)
+	}
+	if len(lvl.prices) != 1 {
+		t.Errorf("expected unchanged price to be recorded once, got %d prices", len(lvl.prices))
+	}
+	if prev, ok := lvl.recordPrice(metadata{Price: 900, Currency: "EUR"}, now.Add(2*time.Hour)); !ok || prev.price != 1000 {
+		t.Errorf("expected previous price 1000, got %v", prev)
+	}
+	if pct, ok := lvl.priceChange(); !ok || pct != -10 {
+		t.Errorf("expected price change of -10%%, got %f", pct)
+	}
+
+	for i := 0; i < 2*priceHistoryMax; i++ {
+		lvl.recordPrice(metadata{Price: float64(i + 1), Currency: "EUR"}, now)
+	}
+	if len(lvl.prices) != priceHistoryMax {
+		t.Errorf("expected %d prices, got %d", priceHistoryMax, len(lvl.prices))
+	}
+}
+
+func TestPriceAlerts(t *testing.T) {
+	eur := func(price float64) pricePoint {
+		return pricePoint{price: price, currency: "EUR"}
+	}
+	tests := []struct {
+		p         product
+		prev, cur pricePoint
+		changePct float64
+		alerts    int
+	}{
+		{product{}, eur(1000), eur(1000), 5, 0},
+		{product{}, eur(1000), eur(990), 5, 0},
+		{product{}, eur(1000), eur(950), 5, 1},
+		{product{}, eur(1000), eur(1100), 5, 1},
+		{product{}, eur(1000), eur(1100), 0, 0},
+		{product{}, eur(1000), pricePoint{price: 500, currency: "USD"}, 5, 0},
+		{product{}, pricePoint{}, eur(1000), 5, 0},
+		{product{Below: 950}, eur(1000), eur(990), 5, 0},
+		{product{Below: 950}, eur(1000), eur(950), 0, 1},
+		{product{Below: 950}, eur(950), eur(900), 0, 0},
+		{product{Below: 950}, eur(1000), eur(900), 5, 2},
+		{product{Above: 1050}, eur(1000), eur(1050), 0, 1},
+		{product{Above: 1050}, eur(1100), eur(1000), 0, 0},
+	}
+
+	for i, tt := range tests {
+		alerts := priceAlerts(tt.p, "Charlie Sneaker", tt.prev, tt.cur, tt.changePct)
+		if len(alerts) != tt.alerts {
+			t.Errorf("%d: expected %d alerts, got %v", i, tt.alerts, alerts)
+		}
+	}
+}
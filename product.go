@@ -1,83 +1,236 @@
 package vuitton
 
 import (
-	"net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
 var productRegExp = regexp.MustCompile(`nvprod[0-9a-z]*`)
 
+// modelRegExp matches model codes, which are also used as SKU's, eg. "M40995" or "1A9JN8".
+// Codes consisting of digits only are legacy product ID's, see productID.
+var modelRegExp = regexp.MustCompile(`^(?:[A-Z][0-9]{5}|[0-9][A-Z0-9]{5})$`)
+
+// numberRegExp matches legacy, numeric product ID's, eg. "005656".
+var numberRegExp = regexp.MustCompile(`^[0-9]+$`)
+
+// modelQueryKeys are the query parameters that may hold a model code.
+var modelQueryKeys = []string{"sku", "model", "ref"}
+
+// priority determines which products are checked first when there isn't enough request budget for all of them.
+type priority int
+
+const (
+	priorityLow priority = iota - 1
+	priorityNormal
+	priorityHigh
+)
+
+// parsePriority converts a priority name into a priority. It returns false if the name is unknown.
+func parsePriority(s string) (priority, bool) {
+	switch strings.ToLower(s) {
+	case "low":
+		return priorityLow, true
+	case "normal", "":
+		return priorityNormal, true
+	case "high":
+		return priorityHigh, true
+	}
+	return priorityNormal, false
+}
+
+func (p priority) String() string {
+	switch p {
+	case priorityLow:
+		return "low"
+	case priorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
 // product represents a single Louis Vuitton product.
 type product struct {
-	URL string
+	URL      string
+	Interval time.Duration // Interval between availability checks, zero means MainLoop.AvailabilityInterval.
+	Priority priority
+	Country  Country // Country to check availability in, overrides the URL's locale. Empty means infer from URL.
+	ID       string  // Product ID resolved via the API, for URL's that don't contain one. See MainLoop.resolveIDs.
+	Sizes    string  // Comma-separated size labels, which are resolved to SKU's before availability checks.
+	Below    float64 // Notify when the price drops to or below this threshold, zero disables.
+	Above    float64 // Notify when the price rises to or above this threshold, zero disables.
+	line     int     // Line in the P-file that the product was read from, zero if unknown.
 }
 
 // Valid returns true if the product URL looks valid, ie. points to louisvuitton.com and looks like a product URL.
 func (p product) Valid() bool {
-	if p.URL == "" ||
-		!strings.HasPrefix(p.URL, "https://") ||
-		!strings.Contains(p.URL, "louisvuitton") ||
-		!strings.Contains(p.URL, "products") {
-		return false
-	}
-	return true
+	return p.validate() == nil
 }
 
-// Domain returns the base domain of the product's URL.
+// validate returns nil if the product URL looks valid, or a *urlError explaining why it isn't.
+func (p product) validate() error {
+	_, err := parseProductURL(p.URL)
+	return err
+}
+
+// Domain returns the base domain of the product's URL, including the scheme.
 // If the product URL is invalid, an empty string is returned.
 func (p product) Domain() string {
-	if !p.Valid() {
-		return ""
-	}
-	parts := strings.SplitAfter(p.URL, ".com")
-	if len(parts) != 2 {
+	pu, err := parseProductURL(p.URL)
+	if err != nil {
 		return ""
 	}
-	return parts[0]
+	return pu.scheme + "://" + pu.host
 }
 
 // productID returns the product ID for the product identified by its URL.
-// productID does this be extracting the product code (usually prefixed with "nvprod") from the URL.
+// productID does this be extracting the product code (usually prefixed with "nvprod") from the URL's path or query.
+// Products that have been resolved via the API return the resolved ID.
 // If the product URL is invalid, or doesn't contain a product ID, an empty string is returned.
 func (p product) productID() string {
 	// Example URL: https://en.louisvuitton.com/eng-nl/products/ecorce-rousse-perfumed-candle-nvprod1910068v.
 	// Result: nvprod1910068v.
 	// This must also work: https://en.louisvuitton.com/eng-nl/products/pochette-accessoires-monogram-005656.
 	// Result: 005656.
-	if !p.Valid() {
+	// And this: https://en.louisvuitton.com/eng-nl/search?productId=nvprod1910068v.
+	// Result: nvprod1910068v.
+	if p.ID != "" {
+		return p.ID
+	}
+	pu, err := parseProductURL(p.URL)
+	if err != nil {
 		return ""
 	}
-	match := productRegExp.FindString(p.URL)
+	match := productRegExp.FindString(strings.ToLower(pu.path))
 	if match != "" {
 		return match
 	}
-	// No nvprod ID, so let's look for a number.
-	u, err := url.Parse(p.URL)
-	if err == nil {
-		parts := strings.Split(u.Path, "/")
-		for i := range parts {
-			if parts[i] == "products" && i < len(parts) && strings.Contains(parts[i+1], "-") {
-				fields := strings.Split(parts[i+1], "-")
-				return fields[len(fields)-1]
+	for _, vs := range pu.query {
+		for _, v := range vs {
+			if match = productRegExp.FindString(strings.ToLower(v)); match != "" {
+				return match
 			}
 		}
 	}
+	// No nvprod ID, so let's look for a number.
+	if token := lastToken(pu.slug); numberRegExp.MatchString(token) {
+		return token
+	}
 	return ""
 }
 
+// modelCode returns the model code for the product identified by its URL, if the URL contains one rather than a
+// product ID. Model codes are found at the end of the path, or in one of the query parameters in modelQueryKeys.
+// The model code must be resolved to a product ID via the API, see MainLoop.resolveIDs.
+// If the product URL is invalid, or doesn't contain a model code, an empty string is returned.
+func (p product) modelCode() string {
+	// Example URL: https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-M40995.
+	// Result: M40995.
+	pu, err := parseProductURL(p.URL)
+	if err != nil {
+		return ""
+	}
+	if token := strings.ToUpper(lastToken(pu.slug)); modelRegExp.MatchString(token) && !numberRegExp.MatchString(token) {
+		return token
+	}
+	for _, key := range modelQueryKeys {
+		if code := strings.ToUpper(pu.query.Get(key)); modelRegExp.MatchString(code) && !numberRegExp.MatchString(code) {
+			return code
+		}
+	}
+	return ""
+}
+
+// lastToken returns the last dash-separated token of a path segment, eg. "M40995" for "neverfull-mm-M40995".
+// lastToken returns the entire segment if it contains no dashes.
+func lastToken(segment string) string {
+	fields := strings.Split(segment, "-")
+	return fields[len(fields)-1]
+}
+
 // SKU returns the SKU for the product identified by its URL.
-// SKU does this by extracting the SKU (usually prefixed with "#") from the URL.
+// SKU does this by extracting the SKU (usually prefixed with "#") from the URL. If the URL lists several SKU's, the
+// first one is returned; use expand to get a product per SKU.
 // If the product URL is invalid, or doesn't contain an SKU, an empty string is returned.
 func (p product) SKU() string {
 	// Example URL: https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8.
 	// Result: 1A9JN8.
-	if !p.Valid() || !strings.ContainsRune(p.URL, '#') {
+	skus := p.SKUs()
+	if len(skus) == 0 {
+		return ""
+	}
+	return skus[0]
+}
+
+// SKUs returns the SKU's for the product identified by its URL.
+// SKUs does this by extracting the comma-separated SKU's (prefixed with "#") from the URL.
+// If the product URL is invalid, or doesn't contain any SKU's, an empty slice is returned.
+func (p product) SKUs() []string {
+	// Example URL: https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8,1A9JNC.
+	// Result: [1A9JN8 1A9JNC].
+	pu, err := parseProductURL(p.URL)
+	if err != nil || pu.fragment == "" || strings.ContainsRune(pu.fragment, '#') {
+		return []string{}
+	}
+	skus := make([]string, 0, strings.Count(pu.fragment, ",")+1)
+	for _, sku := range strings.Split(pu.fragment, ",") {
+		if sku = strings.TrimSpace(sku); sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+	return skus
+}
+
+// expand returns a product for each of the SKU's in the product URL, so that each SKU can be tracked separately.
+// If the product URL contains at most one SKU, expand returns the product itself.
+func (p product) expand() []product {
+	skus := p.SKUs()
+	if len(skus) <= 1 {
+		return []product{p}
+	}
+	base := strings.Split(p.URL, "#")[0]
+	ps := make([]product, 0, len(skus))
+	for _, sku := range skus {
+		exp := p
+		exp.URL = base + "#" + sku
+		ps = append(ps, exp)
+	}
+	return ps
+}
+
+// key returns the key that identifies the product's stock level: the product ID, followed by the SKU, if any, and
+// the locale, if any, so that the same product can be tracked in several countries.
+// If the product URL is invalid, or doesn't contain a product ID, an empty string is returned.
+func (p product) key() string {
+	pID := p.productID()
+	if pID == "" {
+		return ""
+	}
+	key := pID
+	if sku := p.SKU(); sku != "" {
+		key += "#" + sku
+	}
+	locale := p.locale()
+	if p.Country != "" {
+		locale = p.Country.Code()
+	}
+	if locale != "" {
+		key += "@" + locale
+	}
+	return key
+}
+
+// locale returns the API locale found in the product URL, eg. "eng-nl", if it's a supported one.
+// If the product URL is invalid, or doesn't contain a supported locale, an empty string is returned.
+func (p product) locale() string {
+	pu, err := parseProductURL(p.URL)
+	if err != nil {
 		return ""
 	}
-	parts := strings.Split(p.URL, "#")
-	if len(parts) != 2 {
+	if _, ok := localeCountry(pu.locale); !ok {
 		return ""
 	}
-	return strings.TrimSpace(parts[1])
+	return pu.locale
 }
@@ -1,6 +1,10 @@
 package vuitton
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
 	"testing"
 )
 
@@ -19,6 +23,14 @@ func TestValid(t *testing.T) {
 		{"https://en.louisvuitton.com/eng-nl/products/croisillon-shawl-nvprod3390166v#M77459", true},
 		{"https://en.louisvuitton.com/eng-nl/products/ecorce-rousse-perfumed-candle-nvprod1910068v", true},
 		{"https://en.louisvuitton.com/eng-nl/products/pochette-accessoires-monogram-005656", true},
+		{"https://evil.com/louisvuitton/products", false},
+		{"https://louisvuitton.evil.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", false},
+		{"https://evillouisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", false},
+		{"https://en.louisvuitton.com/eng-nl/homepage", false},
+		{"https://en.louisvuitton.com/eng-nl/products-overview/loop-bag", false},
+		{"https://www.louisvuitton.cn/zhs-cn/products/loop-bag-monogram-nvprod3190103v", true},
+		{"https://www.louisvuitton.co.jp/jpn-jp/products/loop-bag-monogram-nvprod3190103v", true},
+		{"https://EN.LouisVuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", true},
 	}
 
 	var valid bool
@@ -43,6 +55,9 @@ func TestDomain(t *testing.T) {
 		{"https://en.louisvuitton.com/eng-nl/products/croisillon-shawl-nvprod3390166v#M77459", "https://en.louisvuitton.com"},
 		{"https://en.louisvuitton.com/eng-nl/products/ecorce-rousse-perfumed-candle-nvprod1910068v", "https://en.louisvuitton.com"},
 		{"https://en.louisvuitton.com/eng-nl/products/pochette-accessoires-monogram-005656", "https://en.louisvuitton.com"},
+		{"https://www.louisvuitton.cn/zhs-cn/products/loop-bag-monogram-nvprod3190103v", "https://www.louisvuitton.cn"},
+		{"https://www.louisvuitton.co.jp/jpn-jp/products/loop-bag-monogram-nvprod3190103v", "https://www.louisvuitton.co.jp"},
+		{"https://evil.com/louisvuitton/products", ""},
 	}
 
 	var actual string
@@ -67,6 +82,14 @@ func TestProductID(t *testing.T) {
 		{"https://en.louisvuitton.com/eng-nl/products/croisillon-shawl-nvprod3390166v#M77459", "nvprod3390166v"},
 		{"https://en.louisvuitton.com/eng-nl/products/ecorce-rousse-perfumed-candle-nvprod1910068v", "nvprod1910068v"},
 		{"https://en.louisvuitton.com/eng-nl/products/pochette-accessoires-monogram-005656", "005656"},
+		{"https://en.louisvuitton.com/eng-nl/products", ""},
+		{"https://en.louisvuitton.com/products", ""},
+		{"https://www.louisvuitton.cn/zhs-cn/products/loop-bag-monogram-nvprod3190103v", "nvprod3190103v"},
+		{"https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-M40995", ""},
+		{"https://en.louisvuitton.com/eng-nl/products/some-bag-abc", ""},
+		{"https://en.louisvuitton.com/eng-nl/search?productId=nvprod3190103v", "nvprod3190103v"},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag?id=NVPROD3190103V", "nvprod3190103v"},
+		{"https://m.louisvuitton.com/mobile/eng-nl/products/loop-bag-monogram-nvprod3190103v", "nvprod3190103v"},
 	}
 
 	var actual string
@@ -104,3 +127,247 @@ func TestSKU(t *testing.T) {
 		}
 	}
 }
+
+func TestSKUs(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", "[]"},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", "[]"},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8", "[1A9JN8]"},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8,1A9JNC", "[1A9JN8 1A9JNC]"},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8, 1A9JNC,", "[1A9JN8 1A9JNC]"},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#,", "[]"},
+	}
+
+	var actual string
+	for _, tt := range tests {
+		p := product{URL: tt.in}
+		actual = fmt.Sprint(p.SKUs())
+		if actual != tt.out {
+			t.Errorf("expected %q, got %q", tt.out, actual)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", []string{"nvprod3190103v@eng-nl"}},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8", []string{"nvprod3130266v#1A9JN8@eng-nl"}},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8,1A9JNC", []string{"nvprod3130266v#1A9JN8@eng-nl", "nvprod3130266v#1A9JNC@eng-nl"}},
+	}
+
+	for _, tt := range tests {
+		p := product{URL: tt.in, Priority: priorityHigh}
+		ps := p.expand()
+		if len(ps) != len(tt.out) {
+			t.Errorf("%s: expected %d products, got %d", tt.in, len(tt.out), len(ps))
+			continue
+		}
+		for i := range ps {
+			if ps[i].key() != tt.out[i] {
+				t.Errorf("%s: expected %q, got %q", tt.in, tt.out[i], ps[i].key())
+			}
+			if ps[i].Priority != priorityHigh {
+				t.Errorf("%s: expected options to be kept", tt.in)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", nil},
+		{"", errMalformedURL},
+		{"hello", errMalformedURL},
+		{"https://", errMalformedURL},
+		{"http://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", errNotHTTPS},
+		{"https://evil.com/louisvuitton/products", errUnknownHost},
+		{"https://en.louisvuitton.com/eng-nl/homepage", errNotProduct},
+	}
+
+	for _, tt := range tests {
+		p := product{URL: tt.in}
+		err := p.validate()
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.in, tt.err, err)
+		}
+	}
+}
+
+func TestParseProductURL(t *testing.T) {
+	tests := []struct {
+		in, locale, slug string
+	}{
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", "eng-nl", "loop-bag-monogram-nvprod3190103v"},
+		{"https://jp.louisvuitton.com/jpn-jp/products/loop-bag-monogram-nvprod3190103v#M12345", "jpn-jp", "loop-bag-monogram-nvprod3190103v"},
+		{"https://en.louisvuitton.com/products/loop-bag-monogram-nvprod3190103v", "", "loop-bag-monogram-nvprod3190103v"},
+		{"https://en.louisvuitton.com/eng-nl/products/", "eng-nl", ""},
+		{"https://en.louisvuitton.com/eng-nl/products", "eng-nl", ""},
+	}
+
+	for _, tt := range tests {
+		pu, err := parseProductURL(tt.in)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.in, err.Error())
+			continue
+		}
+		if pu.locale != tt.locale || pu.slug != tt.slug {
+			t.Errorf("%s: expected %q and %q, got %q and %q", tt.in, tt.locale, tt.slug, pu.locale, pu.slug)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		p   product
+		out string
+	}{
+		{product{URL: "hello"}, ""},
+		{product{URL: "https://en.louisvuitton.com/eng-nl/products/"}, ""},
+		{product{URL: "https://en.louisvuitton.com/products/loop-bag-monogram-nvprod3190103v"}, "nvprod3190103v"},
+		{product{URL: "https://en.louisvuitton.com/xxx-xx/products/loop-bag-monogram-nvprod3190103v"}, "nvprod3190103v"},
+		{product{URL: "https://jp.louisvuitton.com/jpn-jp/products/charlie-trainers-nvprod3130266v#1A9JN8"}, "nvprod3130266v#1A9JN8@jpn-jp"},
+		{product{URL: "https://jp.louisvuitton.com/jpn-jp/products/charlie-trainers-nvprod3130266v", Country: "US"}, "nvprod3130266v@eng-us"},
+	}
+
+	var actual string
+	for _, tt := range tests {
+		actual = tt.p.key()
+		if actual != tt.out {
+			t.Errorf("%s: expected %q, got %q", tt.p.URL, tt.out, actual)
+		}
+	}
+}
+
+func TestCountry(t *testing.T) {
+	tests := []struct {
+		p   product
+		out Country
+	}{
+		{product{URL: "https://en.louisvuitton.com/products/loop-bag-monogram-nvprod3190103v"}, "DK"},
+		{product{URL: "https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v"}, "DK"},
+		{product{URL: "https://jp.louisvuitton.com/jpn-jp/products/loop-bag-monogram-nvprod3190103v"}, "JP"},
+		{product{URL: "https://uk.louisvuitton.com/eng-gb/products/loop-bag-monogram-nvprod3190103v"}, "UK"},
+		{product{URL: "https://jp.louisvuitton.com/jpn-jp/products/loop-bag-monogram-nvprod3190103v", Country: "FR"}, "FR"},
+	}
+
+	m := MainLoop{Country: "DK"}
+	var actual Country
+	for _, tt := range tests {
+		actual = m.country(tt.p)
+		if actual != tt.out {
+			t.Errorf("%s: expected %q, got %q", tt.p.URL, tt.out, actual)
+		}
+	}
+}
+
+func TestModelCode(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"https://en.louisvuitton.com/eng-nl/products/", ""},
+		{"https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", ""},
+		{"https://en.louisvuitton.com/eng-nl/products/pochette-accessoires-monogram-005656", ""},
+		{"https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-M40995", "M40995"},
+		{"https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-m40995", "M40995"},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-1A9JN8#1A9JNC", "1A9JN8"},
+		{"https://en.louisvuitton.com/eng-nl/search?sku=M40995", "M40995"},
+		{"https://en.louisvuitton.com/eng-nl/search?sku=nope", ""},
+	}
+
+	var actual string
+	for _, tt := range tests {
+		p := product{URL: tt.in}
+		actual = p.modelCode()
+		if actual != tt.out {
+			t.Errorf("%s: expected %q, got %q", tt.in, tt.out, actual)
+		}
+	}
+}
+
+// sampleLines returns the lines of products_sample.txt, plus some malformed ones, to seed the fuzz corpus.
+func sampleLines(f *testing.F) []string {
+	bytes, err := ioutil.ReadFile("products_sample.txt")
+	if err != nil {
+		f.Fatal(err)
+	}
+	lines := strings.Split(string(bytes), "\n")
+	return append(lines,
+		"",
+		"#",
+		"https://en.louisvuitton.com",
+		"https://en.louisvuitton.com/eng-nl/products",
+		"https://en.louisvuitton.com/eng-nl/products/",
+		"https://en.louisvuitton.com/eng-nl/products/-",
+		"https://en.louisvuitton.com/eng-nl/products/nvprod3130266v#",
+		"https://en.louisvuitton.com/eng-nl/products/nvprod3130266v#,, ,",
+		"https://en.louisvuitton.com/eng-nl/products/nvprod3130266v##1A9JN8",
+		"https://en.louisvuitton.com/eng-nl/search?productId=nvprod1910068v&dwvar_M40995=1",
+		"nvprod3130266v#1A9JN8,1A9JNC",
+		"M40995",
+	)
+}
+
+func FuzzProduct(f *testing.F) {
+	for _, l := range sampleLines(f) {
+		f.Add(l)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		p := product{URL: raw}
+		valid := p.Valid()
+		if !valid && (p.Domain() != "" || p.productID() != "" || p.modelCode() != "" || p.SKU() != "" || p.locale() != "") {
+			t.Errorf("expected nothing from invalid URL %q", raw)
+		}
+		if valid && p.Domain() == "" {
+			t.Errorf("expected domain for valid URL %q", raw)
+		}
+		if key := p.key(); (key == "") != (p.productID() == "") {
+			t.Errorf("expected key %q only with product ID %q for %q", key, p.productID(), raw)
+		}
+
+		skus := p.SKUs()
+		for _, sku := range skus {
+			if sku == "" || sku != strings.TrimSpace(sku) || strings.ContainsAny(sku, "#,") {
+				t.Errorf("invalid SKU %q in %q", sku, raw)
+			}
+		}
+		if sku := p.SKU(); (len(skus) == 0 && sku != "") || (len(skus) > 0 && sku != skus[0]) {
+			t.Errorf("expected first of %q as SKU for %q, got %q", skus, raw, sku)
+		}
+
+		ps := p.expand()
+		if len(skus) > 1 && len(ps) != len(skus) || len(skus) <= 1 && len(ps) != 1 {
+			t.Fatalf("expected %d products for %q, got %d", len(skus), raw, len(ps))
+		}
+		for i, exp := range ps {
+			if len(skus) > 1 && (exp.SKU() != skus[i] || exp.productID() != p.productID()) {
+				t.Errorf("expected SKU %q and product ID %q for %q, got %q and %q", skus[i], p.productID(), exp.URL, exp.SKU(), exp.productID())
+			}
+		}
+	})
+}
+
+func FuzzBareURL(f *testing.F) {
+	for _, l := range sampleLines(f) {
+		f.Add(l, "dk")
+	}
+	f.Add("nvprod3130266v#1A9JN8", "")
+	f.Add("M40995", "jp")
+	f.Fuzz(func(t *testing.T, s, c string) {
+		u, ok := bareURL(s, Country(c))
+		if !ok {
+			return
+		}
+		if p := (product{URL: u}); !p.Valid() || p.productID() == "" && p.modelCode() == "" {
+			t.Errorf("expected valid product URL with an ID or model code for %q in %q, got %q", s, c, u)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package vuitton
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Proxy selection strategies, see ProxyPool.
+const (
+	RoundRobin        = "roundrobin" // Use each proxy in turn.
+	LeastRecentlyUsed = "lru"        // Use the proxy that has been idle the longest.
+)
+
+// proxyKey is the context key under which the proxy chosen for a request is stored.
+type proxyKey struct{}
+
+// proxyStats keeps track of the health of a single proxy.
+type proxyStats struct {
+	url          *url.URL
+	requests     int
+	failures     int
+	consecutive  int // Failures since the most recent success.
+	lastUsed     time.Time
+	benchedUntil time.Time // The proxy isn't used before this time, unless every proxy is benched.
+}
+
+// ProxyPool spreads requests across a number of proxies. Proxies that fail MaxFailures requests in a row are benched
+// for BenchDuration. A request fails if it can't be sent, or if the response signals that the proxy is rate limited
+// or blocked. ProxyPool is safe for concurrent use. Unless Clock is set, a MainLoop that uses the pool sets it to
+// MainLoop.Clock when it runs.
+type ProxyPool struct {
+	Strategy      string        // RoundRobin or LeastRecentlyUsed.
+	MaxFailures   int           // Consecutive failures before a proxy is benched.
+	BenchDuration time.Duration // How long a failing proxy is benched.
+	Clock         Clock         // Tells when proxies were used and how long they're benched. Nil means the system clock.
+
+	sync.Mutex // Protects the field(s) below.
+	proxies    []*proxyStats
+	next       int // Index of the next proxy to consider, when using RoundRobin.
+}
+
+// LoadProxyPool reads proxy URL's from the given file, one per line. Empty lines and lines starting with "#" are
+// ignored.
+func LoadProxyPool(filename, strategy string, maxFailures int, bench time.Duration) (*ProxyPool, error) {
+	if strategy != RoundRobin && strategy != LeastRecentlyUsed {
+		return nil, fmt.Errorf("unknown proxy selection strategy %q, must be %s or %s", strategy, RoundRobin, LeastRecentlyUsed)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	pool := &ProxyPool{Strategy: strategy, MaxFailures: maxFailures, BenchDuration: bench}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		u, err := parseProxyURL(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		pool.proxies = append(pool.proxies, &proxyStats{url: u})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(pool.proxies) == 0 {
+		return nil, fmt.Errorf("no proxies in %s", filename)
+	}
+	return pool, nil
+}
+
+// parseProxyURL parses and validates the URL of a proxy.
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", raw)
+	}
+	if !proxySchemes[u.Scheme] {
+		return nil, fmt.Errorf("invalid proxy URL %q, scheme must be http, https or socks5", raw)
+	}
+	return u, nil
+}
+
+// now returns the current time according to ProxyPool.Clock.
+func (p *ProxyPool) now() time.Time {
+	if p.Clock == nil {
+		return systemClock{}.Now()
+	}
+	return p.Clock.Now()
+}
+
+// pick returns the proxy to use for the next request. Benched proxies are skipped, unless every proxy is benched, in
+// which case the proxy that comes off the bench first is returned.
+func (p *ProxyPool) pick(now time.Time) *proxyStats {
+	p.Lock()
+	defer p.Unlock()
+
+	var picked *proxyStats
+	n := len(p.proxies)
+	for i := 0; i < n; i++ {
+		idx := (p.next + i) % n
+		px := p.proxies[idx]
+		if px.benchedUntil.After(now) {
+			continue
+		}
+		if p.Strategy == RoundRobin {
+			picked = px
+			p.next = idx + 1
+			break
+		}
+		if picked == nil || px.lastUsed.Before(picked.lastUsed) {
+			picked = px
+		}
+	}
+	if picked == nil {
+		for _, px := range p.proxies {
+			if picked == nil || px.benchedUntil.Before(picked.benchedUntil) {
+				picked = px
+			}
+		}
+	}
+	picked.lastUsed = now
+	return picked
+}
+
+// report records the outcome of a request sent through px.
+func (p *ProxyPool) report(px *proxyStats, ok bool, now time.Time) {
+	p.Lock()
+	defer p.Unlock()
+
+	px.requests++
+	if ok {
+		px.consecutive = 0
+		return
+	}
+	px.failures++
+	px.consecutive++
+	if p.MaxFailures > 0 && px.consecutive >= p.MaxFailures {
+		px.benchedUntil = now.Add(p.BenchDuration)
+		px.consecutive = 0
+	}
+}
+
+// proxy returns the proxy chosen for the request by poolTransport. It is used as http.Transport.Proxy.
+func (p *ProxyPool) proxy(req *http.Request) (*url.URL, error) {
+	if px, ok := req.Context().Value(proxyKey{}).(*proxyStats); ok {
+		return px.url, nil
+	}
+	return nil, nil
+}
+
+// rows returns a row for each proxy with its URL, number of requests and failures, and status.
+func (p *ProxyPool) rows(now time.Time) [][]string {
+	p.Lock()
+	defer p.Unlock()
+
+	rows := make([][]string, 0, len(p.proxies))
+	for _, px := range p.proxies {
+		status := "OK"
+		if px.benchedUntil.After(now) {
+			status = "Benched until " + px.benchedUntil.Format("15:04:05")
+		}
+		rows = append(rows, []string{px.url.Redacted(), strconv.Itoa(px.requests), strconv.Itoa(px.failures), status})
+	}
+	return rows
+}
+
+// poolTransport sends each request through a proxy from the pool, and reports the outcome back to the pool.
+type poolTransport struct {
+	pool *ProxyPool
+	next http.RoundTripper // Must use pool.proxy as its Proxy function.
+}
+
+func (t poolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	px := t.pool.pick(t.pool.now())
+	resp, err := t.next.RoundTrip(req.WithContext(context.WithValue(req.Context(), proxyKey{}, px)))
+	ok := err == nil
+	if ok {
+		switch resp.StatusCode {
+		case http.StatusForbidden, http.StatusProxyAuthRequired, http.StatusTooManyRequests:
+			ok = false
+		}
+	}
+	t.pool.report(px, ok, t.pool.now())
+	return resp, err
+}
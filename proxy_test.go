@@ -0,0 +1,136 @@
+package vuitton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newProxyStandIn returns a plain HTTP proxy stand-in, which answers every request itself with the given status code,
+// and counts the requests it receives.
+func newProxyStandIn(t *testing.T, status int, count *int) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*count++
+		w.WriteHeader(status)
+		_, _ = fmt.Fprint(w, `{"skuAvailability": []}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func writeProxyFile(t *testing.T, lines ...string) string {
+	filename := filepath.Join(t.TempDir(), "proxies.txt")
+	if err := ioutil.WriteFile(filename, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestProxyPool(t *testing.T) {
+	var good, limited int
+	filename := writeProxyFile(t,
+		"# Proxies",
+		newProxyStandIn(t, http.StatusOK, &good),
+		"",
+		newProxyStandIn(t, http.StatusTooManyRequests, &limited),
+	)
+	pool, err := LoadProxyPool(filename, RoundRobin, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := newFakeClock()
+	pool.Clock = clock
+	c, err := NewClient(ClientConfig{ProxyPool: pool, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(n int) {
+		for i := 0; i < n; i++ {
+			resp, err := c.Get("http://api.louisvuitton.com/api/eng-nl/catalog/availability/nvprod3130266v")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = resp.Body.Close()
+		}
+	}
+	get(10)
+
+	// Requests alternate until the rate limited proxy has failed twice, then it's benched.
+	if good != 8 || limited != 2 {
+		t.Errorf("expected 8 and 2 requests, got %d and %d", good, limited)
+	}
+	rows := pool.rows(clock.Now())
+	if fmt.Sprint(rows[0][1:]) != "[8 0 OK]" {
+		t.Errorf("expected stats [8 0 OK] for good proxy, got %v", rows[0][1:])
+	}
+	if fmt.Sprint(rows[1][1:]) != "[2 2 Benched until 13:00:00]" {
+		t.Errorf("expected limited proxy to be benched until 13:00:00, got %v", rows[1][1:])
+	}
+
+	// The bench is timed by the pool's clock.
+	clock.Advance(time.Hour)
+	get(2)
+	if good != 9 || limited != 3 {
+		t.Errorf("expected 9 and 3 requests once the bench is over, got %d and %d", good, limited)
+	}
+}
+
+func TestProxyPoolPick(t *testing.T) {
+	now := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	filename := writeProxyFile(t, "http://a:8080", "socks5://b:1080", "https://user:secret@c:8443")
+
+	tests := []struct {
+		strategy string
+		benched  []int
+		expected string
+	}{
+		{RoundRobin, nil, "a b c a b c"},
+		{RoundRobin, []int{1}, "a c a c a c"},
+		{LeastRecentlyUsed, nil, "a b c a b c"},
+		{LeastRecentlyUsed, []int{0, 2}, "b b b b b b"},
+		{LeastRecentlyUsed, []int{0, 1, 2}, "a a a a a a"},
+	}
+	for _, test := range tests {
+		pool, err := LoadProxyPool(filename, test.strategy, 1, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, i := range test.benched {
+			pool.report(pool.proxies[i], false, now.Add(time.Duration(i)*time.Second))
+		}
+		picks := make([]string, 0, 6)
+		for i := 0; i < 6; i++ {
+			picks = append(picks, pool.pick(now.Add(time.Duration(i)*time.Second)).url.Hostname())
+		}
+		if actual := strings.Join(picks, " "); actual != test.expected {
+			t.Errorf("expected %q for %s with %v benched, got %q", test.expected, test.strategy, test.benched, actual)
+		}
+	}
+
+	pool, _ := LoadProxyPool(filename, RoundRobin, 1, time.Hour)
+	if rows := pool.rows(now); strings.Contains(fmt.Sprint(rows), "secret") {
+		t.Errorf("expected credentials to be redacted, got %v", rows)
+	}
+}
+
+func TestLoadProxyPoolInvalid(t *testing.T) {
+	tests := []struct {
+		filename string
+		strategy string
+	}{
+		{writeProxyFile(t, "http://a:8080"), "random"},
+		{writeProxyFile(t, "http://a:8080", "ftp://b:21"), RoundRobin},
+		{writeProxyFile(t, "# No proxies"), RoundRobin},
+		{filepath.Join(t.TempDir(), "missing.txt"), RoundRobin},
+	}
+	for _, test := range tests {
+		if _, err := LoadProxyPool(test.filename, test.strategy, 1, time.Hour); err == nil {
+			t.Errorf("expected error for %s with %s", test.filename, test.strategy)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package vuitton
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// fixtureNameMax is the maximum length of the readable part of a fixture's file name.
+const fixtureNameMax = 100
+
+// unsafeRegExp matches the characters that are replaced in fixture file names.
+var unsafeRegExp = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// exchange is a recorded HTTP exchange, as stored in a fixture file. Only the response is kept, the request is
+// identified by the file name.
+type exchange struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// fixtureName returns the name of the fixture file for the given request, eg.
+// "GET_api.louisvuitton.com_api_eng-nl_catalog_availability_nvprod3130266v_1a2b3c4d.json". The name ends in a hash of
+// the method and URL, so that requests whose URL's only differ in replaced characters don't share a fixture.
+func fixtureName(req *http.Request) string {
+	u := *req.URL
+	u.Fragment = ""
+	raw := req.Method + " " + u.String()
+	name := req.Method + "_" + u.Host + u.Path
+	if u.RawQuery != "" {
+		name += "_" + u.RawQuery
+	}
+	name = unsafeRegExp.ReplaceAllString(name, "_")
+	if len(name) > fixtureNameMax {
+		name = name[:fixtureNameMax]
+	}
+	sum := sha1.Sum([]byte(raw))
+	return name + "_" + hex.EncodeToString(sum[:4]) + ".json"
+}
+
+// recordTransport sends requests via next, and records each exchange to a fixture file in dir. Existing fixtures are
+// overwritten, so the most recent exchange for each request is kept.
+type recordTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	u := *req.URL
+	u.Fragment = ""
+	ex := exchange{Method: req.Method, URL: u.String(), Status: resp.StatusCode, Header: resp.Header, Body: string(body)}
+	bytes, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(t.dir, fixtureName(req)), bytes, 0644); err != nil {
+		return nil, fmt.Errorf("unable to record response: %w", err)
+	}
+	return resp, nil
+}
+
+// replayTransport serves responses from the fixture files in dir, as recorded by recordTransport. It never touches
+// the network. Requests that weren't recorded fail.
+type replayTransport struct {
+	dir string
+}
+
+func (t replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	bytes, err := ioutil.ReadFile(filepath.Join(t.dir, fixtureName(req)))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ex exchange
+	if err := json.Unmarshal(bytes, &ex); err != nil {
+		return nil, fmt.Errorf("invalid fixture for %s %s: %w", req.Method, req.URL, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ex.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(ex.Body)),
+		ContentLength: int64(len(ex.Body)),
+		Request:       req,
+	}, nil
+}
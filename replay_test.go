@@ -0,0 +1,121 @@
+package vuitton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAvailabilityReplay(t *testing.T) {
+	m := &MainLoop{Country: "DK", Client: &http.Client{Transport: replayTransport{dir: "testdata/replay"}}}
+
+	tests := []struct {
+		url      string
+		expected bool
+	}{
+		// Several SKU's, only the requested one counts.
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8", true},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JNC", false},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JNE", false},
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A0000", false},
+		// Several SKU's and none requested, the first one counts.
+		{"https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v", true},
+		{"https://us.louisvuitton.com/eng-us/products/neverfull-mm-monogram-nvprod2990025v", false},
+		{"https://us.louisvuitton.com/eng-us/products/neverfull-mm-monogram-nvprod2990025v#M41177", true},
+		// A single SKU counts, whatever SKU is requested.
+		{"https://en.louisvuitton.com/eng-nl/products/pocket-organiser-damier-graphite-nvprod3430052v", true},
+		{"https://en.louisvuitton.com/eng-nl/products/pocket-organiser-damier-graphite-nvprod3430052v#N00000", true},
+	}
+	for _, test := range tests {
+		inStock, err := m.availability(product{URL: test.url})
+		if err != nil {
+			t.Errorf("unexpected error for %s: %s", test.url, err)
+			continue
+		}
+		if inStock != test.expected {
+			t.Errorf("expected %v for %s, got %v", test.expected, test.url, inStock)
+		}
+	}
+
+	// Requests that weren't recorded fail.
+	_, err := m.availability(product{URL: "https://en.louisvuitton.com/eng-nl/products/nvprod0000000v"})
+	if err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("expected error for request that wasn't recorded, got %v", err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("etag", `"v1"`)
+		if r.URL.Query().Get("q") == "missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "response for %s", r.URL.RequestURI())
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	dir := t.TempDir()
+
+	urls := []string{
+		"https://api.louisvuitton.com/api/eng-nl/catalog/availability/nvprod3130266v",
+		"https://api.louisvuitton.com/api/eng-nl/catalog/search?q=neverfull+mm",
+		"https://api.louisvuitton.com/api/eng-nl/catalog/search?q=neverfull/mm",
+		"https://api.louisvuitton.com/api/eng-nl/catalog/search?q=missing",
+	}
+	get := func(c *http.Client, u string) (int, string, string) {
+		t.Helper()
+		resp, err := c.Get(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("etag"), string(body)
+	}
+
+	recorder := &http.Client{Transport: recordTransport{dir: dir, next: rewriteTransport{target: target}}}
+	recorded := make([]string, 0, len(urls))
+	for _, u := range urls {
+		status, etag, body := get(recorder, u)
+		recorded = append(recorded, fmt.Sprint(status, etag, body))
+	}
+
+	replayer := &http.Client{Transport: replayTransport{dir: dir}}
+	for i, u := range urls {
+		status, etag, body := get(replayer, u)
+		if replayed := fmt.Sprint(status, etag, body); replayed != recorded[i] {
+			t.Errorf("expected %q for %s, got %q", recorded[i], u, replayed)
+		}
+	}
+	if calls != len(urls) {
+		t.Errorf("expected %d calls to the server, got %d", len(urls), calls)
+	}
+}
+
+func TestFixtureName(t *testing.T) {
+	tests := []struct {
+		url    string
+		prefix string
+	}{
+		{"https://api.louisvuitton.com/api/eng-nl/catalog/availability/nvprod3130266v", "GET_api.louisvuitton.com_api_eng-nl_catalog_availability_nvprod3130266v_"},
+		{"https://api.louisvuitton.com/api/eng-nl/catalog/search?q=neverfull+mm", "GET_api.louisvuitton.com_api_eng-nl_catalog_search_q_neverfull_mm_"},
+		{"https://en.louisvuitton.com/eng-nl/products/" + strings.Repeat("x", 200), "GET_en.louisvuitton.com_eng-nl_products_xxx"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, test.url, nil)
+		name := fixtureName(req)
+		if !strings.HasPrefix(name, test.prefix) || !strings.HasSuffix(name, ".json") {
+			t.Errorf("expected name starting with %q for %s, got %q", test.prefix, test.url, name)
+		}
+		if len(name) > fixtureNameMax+len("_12345678.json") {
+			t.Errorf("expected name of at most %d characters, got %d", fixtureNameMax+len("_12345678.json"), len(name))
+		}
+	}
+}
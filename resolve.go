@@ -0,0 +1,106 @@
+package vuitton
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// lvModelURL is the path template of the endpoint that provides product details by model code on Louis Vuitton's API.
+// The response has the same format as the product details response.
+const lvModelURL = "/api/{locale}/catalog/sku/{model}"
+
+// resolveIDs resolves products whose URL's don't contain a product ID: short links are followed to the product page
+// they redirect to, and model codes are looked up via the API. A model code is also the SKU to check, unless the URL
+// lists SKU's of its own.
+// Products that already have a product ID are returned as-is. Products that can't be resolved are left out, and
+// reported via a pFileError, just like lines in the P-file that can't be parsed.
+func (m *MainLoop) resolveIDs(ps []product) ([]product, error) {
+	resolved := make([]product, 0, len(ps))
+	var perr pFileError
+	for _, p := range ps {
+		if isShortLink(p.URL) {
+			final, err := m.follow(p.URL)
+			if err != nil {
+				perr = append(perr, lineError{line: p.line, err: fmt.Errorf("unable to follow link: %w", err)})
+				continue
+			}
+			p.URL = final
+			if err := p.validate(); err != nil {
+				perr = append(perr, lineError{line: p.line, err: fmt.Errorf("link doesn't lead to a product page: %w", err)})
+				continue
+			}
+		}
+		if p.productID() != "" {
+			resolved = append(resolved, p.expand()...)
+			continue
+		}
+		code := p.modelCode()
+		if code == "" {
+			resolved = append(resolved, p) // Reported by the caller.
+			continue
+		}
+		details, err := m.lookupModel(p, code)
+		if err != nil {
+			perr = append(perr, lineError{line: p.line, err: fmt.Errorf("unable to resolve model code %s: %w", code, err)})
+			continue
+		}
+		p.ID = details.ProductID
+		if len(p.SKUs()) == 0 {
+			p.URL = strings.Split(p.URL, "#")[0] + "#" + code
+		}
+		resolved = append(resolved, p.expand()...)
+	}
+	if len(perr) > 0 {
+		return resolved, perr
+	}
+	return resolved, nil
+}
+
+// lookupModel fetches product details for the given model code. Details are cached like those fetched by details.
+func (m *MainLoop) lookupModel(p product, code string) (productDetails, error) {
+	key := cacheKey(m.country(p).Code(), "model/"+code)
+	release := m.detailsCache.acquire(key)
+	defer release()
+	if cached, fresh, _ := m.detailsCache.get(key, detailsTTL, m.now()); fresh {
+		return parseDetails(cached.body)
+	}
+
+	bytes, err := m.getBody(p.URL, m.country(p).Code(), m.apiURL(endpointModel, "{locale}", m.country(p).Code(), "{model}", code))
+	if err != nil {
+		return productDetails{}, err
+	}
+	details, err := parseDetails(bytes)
+	if err != nil {
+		return productDetails{}, err
+	}
+	if details.ProductID == "" {
+		return productDetails{}, errors.New("no product ID in response")
+	}
+	m.detailsCache.put(key, cachedResponse{body: bytes, fetchedAt: m.now()})
+	return details, nil
+}
+
+// follow follows the redirects of a short link, and returns the URL it ends up at. The fragment of the short link,
+// which may list SKU's, is kept if the final URL doesn't have one.
+func (m *MainLoop) follow(raw string) (string, error) {
+	base, fragment := raw, ""
+	if i := strings.IndexByte(raw, '#'); i >= 0 {
+		base, fragment = raw[:i], raw[i+1:]
+	}
+	resp, err := m.Client.Get(base)
+	if err != nil {
+		return "", requestError(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", checkResponse(resp)
+	}
+
+	final := resp.Request.URL
+	if final.Fragment == "" && fragment != "" {
+		return final.String() + "#" + fragment, nil
+	}
+	return final.String(), nil
+}
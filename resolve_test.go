@@ -0,0 +1,86 @@
+package vuitton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// rewriteTransport sends every request to the test server at target, regardless of the request's host, so that
+// requests for the API and the storefront can be served locally.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	return resp, nil
+}
+
+// newTestLoop returns a MainLoop whose client sends every request to the given handler.
+func newTestLoop(t *testing.T, h http.Handler) *MainLoop {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	return &MainLoop{
+		Country: "DK",
+		Client:  &http.Client{Transport: rewriteTransport{target: target}, Timeout: time.Second},
+	}
+}
+
+func TestResolveIDs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/eng-nl/catalog/sku/M40995", func(w http.ResponseWriter, r *http.Request) {
+		bytes, _ := ioutil.ReadFile("testdata/model_M40995.json")
+		_, _ = w.Write(bytes)
+	})
+	mux.HandleFunc("/api/eng-nl/catalog/sku/M00000", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/s/charlie", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v", http.StatusFound)
+	})
+	mux.HandleFunc("/s/home", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://en.louisvuitton.com/eng-nl/homepage", http.StatusFound)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "<html></html>")
+	})
+	m := newTestLoop(t, mux)
+
+	ps := []product{
+		{URL: "https://en.louisvuitton.com/eng-nl/products/loop-bag-monogram-nvprod3190103v", line: 1},
+		{URL: "https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-M40995", line: 2},
+		{URL: "https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-M40995#M41177", line: 3},
+		{URL: "https://en.louisvuitton.com/s/charlie#1A9JN8,1A9JNC", line: 4},
+		{URL: "https://en.louisvuitton.com/eng-nl/products/unknown-bag-M00000", line: 5},
+		{URL: "https://en.louisvuitton.com/s/home", line: 6},
+	}
+	resolved, err := m.resolveIDs(ps)
+
+	keys := make([]string, 0, len(resolved))
+	for _, p := range resolved {
+		keys = append(keys, p.key())
+	}
+	expected := "[nvprod3190103v@eng-nl nvprod2990025v#M40995@eng-nl nvprod2990025v#M41177@eng-nl " +
+		"nvprod3130266v#1A9JN8@eng-nl nvprod3130266v#1A9JNC@eng-nl]"
+	if fmt.Sprint(keys) != expected {
+		t.Errorf("expected %s, got %v", expected, keys)
+	}
+
+	perr, ok := err.(pFileError)
+	if !ok || len(perr) != 2 || perr[0].line != 5 || perr[1].line != 6 {
+		t.Errorf("expected errors for lines 5 and 6, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package vuitton
+
+import "time"
+
+// interval returns the time between two availability checks for the given product.
+// Products use AvailabilityInterval unless they have an interval of their own. Products that were recently seen in
+// stock are "hot", and are checked every HotInterval until HotDuration has passed.
+func (m *MainLoop) interval(lvl stockLevel, now time.Time) time.Duration {
+	d := m.AvailabilityInterval
+	if lvl.product.Interval > 0 {
+		d = lvl.product.Interval
+	}
+	if m.hot(lvl, now) && m.HotInterval < d {
+		d = m.HotInterval
+	}
+	return d
+}
+
+// hot returns true if the product was seen in stock within the last HotDuration.
+func (m *MainLoop) hot(lvl stockLevel, now time.Time) bool {
+	return m.HotInterval > 0 && !lvl.seenAt.IsZero() && now.Sub(lvl.seenAt) < m.HotDuration
+}
+
+// wanted returns the delay between two consecutive availability checks that would allow every product to be checked
+// at its own interval.
+// wanted must be called with the lock held.
+func (m *MainLoop) wanted(now time.Time) time.Duration {
+	if len(m.products) == 0 {
+		return m.AvailabilityInterval
+	}
+	var rate float64 // Checks per second.
+	for _, lvl := range m.products {
+		rate += 1 / m.interval(lvl, now).Seconds()
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// spacing returns the delay between two consecutive availability checks.
+// Checks are spread evenly across time, so that each product is checked once per interval. If a RequestBudget has
+// been set, spacing never drops below what the budget allows, counting requestsPerCheck requests per check, in which
+// case products will be checked less often than their interval, and high-priority products are checked before others.
+// spacing must be called with the lock held.
+func (m *MainLoop) spacing() time.Duration {
+	d := m.wanted(m.now())
+	if m.RequestBudget > 0 {
+		if minD := time.Duration(m.requestsPerCheck()) * time.Minute / time.Duration(m.RequestBudget); d < minD {
+			d = minD
+		}
+	}
+	return d
+}
+
+// cacheTTL returns how long an availability response for the given product may be reused. This is MainLoop.CacheTTL,
+// unless it's CacheUntilDue, in which case a response is reused until half a spacing before the product is due again.
+// Products sharing a product ID are then checked at most once per interval, as the checks of the others fall within
+// that window, while each product still gets a fresh response when its own check comes around.
+// cacheTTL must be called without the lock held.
+func (m *MainLoop) cacheTTL(p product) time.Duration {
+	if m.CacheTTL != CacheUntilDue {
+		return m.CacheTTL
+	}
+	m.Lock()
+	defer m.Unlock()
+	lvl, ok := m.products[p.key()]
+	if !ok {
+		return untrackedCacheTTL
+	}
+	now := m.now()
+	d := m.interval(lvl, now)
+	spacing, wanted := m.spacing(), m.wanted(now)
+	if spacing > wanted {
+		d = time.Duration(float64(d) * float64(spacing) / float64(wanted))
+	}
+	return d - spacing/2
+}
+
+// requestsPerCheck returns the number of API requests an availability check may cost: one online, and one for the
+// boutiques in MainLoop.Stores, if any. Responses shared via the cache may cost less. Product details are left out, as
+// they're cached for a long time, and failures to fetch them for detailsRetry.
+func (m *MainLoop) requestsPerCheck() int {
+	if len(m.Stores) > 0 {
+		return 2
+	}
+	return 1
+}
+
+// effectiveInterval returns the time it takes to check a product with the default interval, given the current spacing.
+// effectiveInterval must be called with the lock held.
+func (m *MainLoop) effectiveInterval() time.Duration {
+	wanted := m.wanted(m.now())
+	spacing := m.spacing()
+	if spacing <= wanted {
+		return m.AvailabilityInterval
+	}
+	return time.Duration(float64(m.AvailabilityInterval) * float64(spacing) / float64(wanted))
+}
+
+// next picks the product that should be checked now, and marks it as checked so that concurrent checks don't pick the
+// same product. Of the products that are due, the one with the highest priority goes first, then the one that has been
+// due the longest. next returns false if no products are due.
+// next must be called with the lock held.
+func (m *MainLoop) next(now time.Time) (string, stockLevel, bool) {
+	// Allow for some slack, so that timer jitter doesn't make us skip a check.
+	deadline := now.Add(m.spacing() / 2)
+
+	var (
+		nextKey string
+		nextLvl stockLevel
+		nextDue time.Time
+		found   bool
+	)
+	for key, lvl := range m.products {
+		due := lvl.checkedAt.Add(m.interval(lvl, now))
+		if lvl.checkedAt.IsZero() {
+			due = time.Time{}
+		}
+		if due.After(deadline) {
+			continue
+		}
+		prio := lvl.product.Priority
+		switch {
+		case !found,
+			prio > nextLvl.product.Priority,
+			prio == nextLvl.product.Priority && due.Before(nextDue),
+			prio == nextLvl.product.Priority && due.Equal(nextDue) && key < nextKey:
+			nextKey, nextLvl, nextDue, found = key, lvl, due, true
+		}
+	}
+	if !found {
+		return "", stockLevel{}, false
+	}
+	nextLvl.checkedAt = now
+	m.products[nextKey] = nextLvl
+	return nextKey, nextLvl, true
+}
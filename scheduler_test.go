@@ -0,0 +1,110 @@
+package vuitton
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSpacing(t *testing.T) {
+	tests := []struct {
+		products int
+		budget   int
+		out      time.Duration
+	}{
+		{0, 0, 30 * time.Second},
+		{1, 0, 30 * time.Second},
+		{30, 0, time.Second},
+		{30, 120, time.Second},
+		{30, 30, 2 * time.Second},
+		{2, 30, 15 * time.Second},
+	}
+
+	var actual time.Duration
+	for _, tt := range tests {
+		m := MainLoop{AvailabilityInterval: 30 * time.Second, RequestBudget: tt.budget}
+		m.products = make(map[string]stockLevel)
+		for i := 0; i < tt.products; i++ {
+			m.products[fmt.Sprintf("nvprod%d", i)] = stockLevel{}
+		}
+		actual = m.spacing()
+		if actual != tt.out {
+			t.Errorf("%d products, budget %d: expected %s, got %s", tt.products, tt.budget, tt.out, actual)
+		}
+	}
+
+	// Checks that include boutiques cost two requests.
+	m := MainLoop{AvailabilityInterval: 30 * time.Second, RequestBudget: 6, Stores: []string{"A29"}}
+	m.products = map[string]stockLevel{"nvprod1": {}, "nvprod2": {}}
+	if actual = m.spacing(); actual != 20*time.Second {
+		t.Errorf("expected 20s with stores, got %s", actual)
+	}
+}
+
+func TestNext(t *testing.T) {
+	now := time.Now()
+	m := MainLoop{AvailabilityInterval: time.Second}
+	m.products = map[string]stockLevel{
+		"a": {checkedAt: now.Add(-time.Second)},
+		"b": {checkedAt: now.Add(-time.Minute)},
+		"c": {},
+	}
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		pID, _, ok := m.next(now.Add(time.Duration(i) * time.Second))
+		if !ok {
+			t.Fatal("expected a product")
+		}
+		order = append(order, pID)
+	}
+	if fmt.Sprint(order) != "[c b a c]" {
+		t.Errorf("expected [c b a c], got %v", order)
+	}
+}
+
+func TestNextPriority(t *testing.T) {
+	now := time.Now()
+	m := MainLoop{AvailabilityInterval: time.Minute}
+	m.products = map[string]stockLevel{
+		"low":    {product: product{Priority: priorityLow}, checkedAt: now.Add(-time.Hour)},
+		"normal": {checkedAt: now.Add(-time.Hour)},
+		"high":   {product: product{Priority: priorityHigh}, checkedAt: now.Add(-2 * time.Minute)},
+		"recent": {product: product{Priority: priorityHigh}, checkedAt: now.Add(-time.Second)},
+	}
+
+	var order []string
+	for {
+		pID, _, ok := m.next(now)
+		if !ok {
+			break
+		}
+		order = append(order, pID)
+	}
+	if fmt.Sprint(order) != "[high normal low]" {
+		t.Errorf("expected [high normal low], got %v", order)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		lvl stockLevel
+		out time.Duration
+	}{
+		{stockLevel{}, 30 * time.Second},
+		{stockLevel{product: product{Interval: time.Hour}}, time.Hour},
+		{stockLevel{product: product{Interval: 5 * time.Second}, seenAt: now.Add(-time.Minute)}, 5 * time.Second},
+		{stockLevel{seenAt: now.Add(-time.Minute)}, 10 * time.Second},
+		{stockLevel{seenAt: now.Add(-time.Hour)}, 30 * time.Second},
+	}
+
+	m := MainLoop{AvailabilityInterval: 30 * time.Second, HotInterval: 10 * time.Second, HotDuration: 10 * time.Minute}
+	var actual time.Duration
+	for i, tt := range tests {
+		actual = m.interval(tt.lvl, now)
+		if actual != tt.out {
+			t.Errorf("%d: expected %s, got %s", i, tt.out, actual)
+		}
+	}
+}
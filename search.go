@@ -0,0 +1,134 @@
+package vuitton
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// lvSearchURL is the path template of the endpoint that provides catalog search on Louis Vuitton's API.
+const lvSearchURL = "/api/{locale}/catalog/search?q={query}"
+
+// searchHit describes a single product found by a catalog search.
+type searchHit struct {
+	ProductID string `json:"identifier"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	Color     string `json:"color"`
+	Offers    offer  `json:"offers"`
+}
+
+// searchResponse describes the part of the search response that we wish to process.
+type searchResponse struct {
+	Hits []searchHit `json:"hits"`
+}
+
+// parseSearch converts a search response body into a list of hits. Hits without a product ID are left out.
+func parseSearch(bytes []byte) ([]searchHit, error) {
+	var resp searchResponse
+	if err := json.Unmarshal(bytes, &resp); err != nil {
+		return nil, decodeError(bytes, err)
+	}
+	hits := make([]searchHit, 0, len(resp.Hits))
+	for _, h := range resp.Hits {
+		if h.ProductID != "" {
+			hits = append(hits, h)
+		}
+	}
+	return hits, nil
+}
+
+// pageURL returns the URL of the hit's product page. If the API didn't provide a usable URL, one is made up from
+// the product ID for the given country.
+func (h searchHit) pageURL(c Country) string {
+	if (product{URL: h.URL}).Valid() {
+		return h.URL
+	}
+	u, _ := bareURL(h.ProductID, c)
+	return u
+}
+
+// search performs a catalog search for the given query in MainLoop.Country.
+func (m *MainLoop) search(query string) ([]searchHit, error) {
+	code := m.Country.Code()
+	referer := fmt.Sprintf("https://%s/%s/search/%s", localeHosts[code], code, url.PathEscape(query))
+	bytes, err := m.getBody(referer, code, m.apiURL(endpointSearch, "{locale}", code, "{query}", url.QueryEscape(query)))
+	if err != nil {
+		return nil, err
+	}
+	return parseSearch(bytes)
+}
+
+// Search performs a catalog search for the given query in MainLoop.Country, and writes the results as a numbered
+// table to w. The results numbered in add, starting from 1, are appended to the P-file.
+func (m *MainLoop) Search(w io.Writer, query string, add []int) error {
+	if strings.TrimSpace(query) == "" {
+		return errors.New("empty search query")
+	}
+	hits, err := m.search(query)
+	if err != nil {
+		return err
+	}
+	if len(hits) == 0 {
+		_, _ = fmt.Fprintf(w, "No products found for %q\n", query)
+		return nil
+	}
+
+	t := tablewriter.NewWriter(w)
+	t.SetHeader([]string{"#", "Product", "Name", "Colour", "Price"})
+	for i, h := range hits {
+		price := metadata{Price: float64(h.Offers.Price), Currency: h.Offers.Currency}.formatPrice()
+		t.Append([]string{strconv.Itoa(i + 1), h.ProductID, h.Name, h.Color, price})
+	}
+	t.Render()
+
+	if len(add) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(add))
+	for _, n := range add {
+		if n < 1 || n > len(hits) {
+			return fmt.Errorf("there is no result number %d", n)
+		}
+		lines = append(lines, hits[n-1].pageURL(m.Country))
+	}
+	if err := m.appendPFile(lines); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "\nAdded %d product(s) to %s\n", len(lines), m.PFileName)
+	return nil
+}
+
+// appendPFile appends the given lines to the P-file, creating it if it doesn't exist.
+func (m *MainLoop) appendPFile(lines []string) error {
+	if m.PFileName == "" {
+		return errors.New("no product file")
+	}
+	existing, err := ioutil.ReadFile(m.PFileName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	f, err := os.OpenFile(m.PFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	b := strings.Builder{}
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		b.WriteString("\n")
+	}
+	for _, l := range lines {
+		b.WriteString(l + "\n")
+	}
+	_, err = f.WriteString(b.String())
+	return err
+}
@@ -0,0 +1,58 @@
+package vuitton
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	var query string
+	m := newTestLoop(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/eng-nl/catalog/search" {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.Query().Get("q")
+		bytes, _ := ioutil.ReadFile("testdata/search_neverfull.json")
+		_, _ = w.Write(bytes)
+	}))
+	m.PFileName = filepath.Join(t.TempDir(), "products.txt")
+	if err := ioutil.WriteFile(m.PFileName, []byte("nvprod3130266v#1A9JN8"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := strings.Builder{}
+	if err := m.Search(&b, "neverfull mm", []int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if query != "neverfull mm" {
+		t.Errorf("expected query %q, got %q", "neverfull mm", query)
+	}
+	for _, e := range []string{"nvprod2990025v", "1500.00 EUR", "Neverfull Pouch", "620.00 EUR", "Added 3 product(s)"} {
+		if !strings.Contains(b.String(), e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "Gift Card") {
+		t.Errorf("expected hit without product ID to be left out, got:\n%s", b.String())
+	}
+
+	bytes, err := ioutil.ReadFile(m.PFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "nvprod3130266v#1A9JN8\n" +
+		"https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-nvprod2990025v\n" +
+		"https://en.louisvuitton.com/eng-nl/products/nvprod2210007v\n" +
+		"https://en.louisvuitton.com/eng-nl/products/nvprod3460161v\n"
+	if string(bytes) != expected {
+		t.Errorf("expected product file:\n%s\ngot:\n%s", expected, bytes)
+	}
+
+	if err := m.Search(&b, "neverfull", []int{4}); err == nil {
+		t.Error("expected error for unknown result number")
+	}
+}
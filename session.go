@@ -0,0 +1,206 @@
+package vuitton
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionRefreshMin is the minimum time between two bootstraps of the session for the same storefront. It keeps a
+// blocked client from visiting the storefront on every request.
+const sessionRefreshMin = time.Minute
+
+// cookieSaveMin is the minimum time between two saves of the cookie jar, unless forced. It keeps an API that refreshes
+// its cookies on every response from costing a write per request.
+const cookieSaveMin = 30 * time.Second
+
+// CookieJar is a cookie jar that can be saved to, and loaded from, a file. Cookies are stored per host that set them.
+// CookieJar is safe for concurrent use.
+type CookieJar struct {
+	Clock Clock // Tells which cookies have expired when saving. Nil means the system clock, or MainLoop.Clock once it runs.
+
+	filename string
+	jar      *cookiejar.Jar
+
+	sync.Mutex                                    // Protects the field(s) below.
+	cookies    map[string]map[string]*http.Cookie // Key is host, then cookie name.
+	changed    bool                               // Whether cookies were set since the most recent saveChanged.
+	savedAt    time.Time                          // Time of the most recent saveChanged that saved.
+}
+
+// NewCookieJar returns a cookie jar that is persisted to the given file. Cookies already in the file are loaded; the
+// jar drops those that have expired. The file doesn't need to exist.
+func NewCookieJar(filename string) (*CookieJar, error) {
+	jar, _ := cookiejar.New(nil) // Never fails.
+	j := &CookieJar{filename: filename, jar: jar, cookies: make(map[string]map[string]*http.Cookie)}
+
+	bytes, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved map[string][]*http.Cookie
+	if err := json.Unmarshal(bytes, &saved); err != nil {
+		return nil, fmt.Errorf("invalid cookie file %s: %w", filename, err)
+	}
+	for host, cookies := range saved {
+		j.SetCookies(&url.URL{Scheme: "https", Host: host, Path: "/"}, cookies)
+	}
+	j.changed = false // Nothing to save until cookies are set.
+	return j, nil
+}
+
+// SetCookies implements http.CookieJar.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.Lock()
+	defer j.Unlock()
+	host := u.Hostname()
+	if j.cookies[host] == nil {
+		j.cookies[host] = make(map[string]*http.Cookie)
+	}
+	for _, c := range cookies {
+		j.cookies[host][c.Name] = c
+	}
+	j.changed = j.changed || len(cookies) > 0
+}
+
+// Cookies implements http.CookieJar.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes the cookies to the jar's file. Expired cookies, according to CookieJar.Clock, are left out.
+func (j *CookieJar) Save() error {
+	j.Lock()
+	now := j.now()
+	saved := make(map[string][]*http.Cookie, len(j.cookies))
+	for host, cookies := range j.cookies {
+		for _, c := range cookies {
+			if c.MaxAge < 0 || (!c.Expires.IsZero() && !c.Expires.After(now)) {
+				continue
+			}
+			saved[host] = append(saved[host], c)
+		}
+	}
+	j.Unlock()
+
+	bytes, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(j.filename, bytes, 0600)
+}
+
+// now returns the current time according to CookieJar.Clock.
+func (j *CookieJar) now() time.Time {
+	if j.Clock == nil {
+		return systemClock{}.Now()
+	}
+	return j.Clock.Now()
+}
+
+// saveChanged saves the cookies if any were set since they were last saved by saveChanged, and that was at least min
+// before now. If saving fails, the cookies are still considered changed, so the next call tries again.
+func (j *CookieJar) saveChanged(now time.Time, min time.Duration) error {
+	j.Lock()
+	if !j.changed || now.Sub(j.savedAt) < min {
+		j.Unlock()
+		return nil
+	}
+	j.changed = false
+	j.savedAt = now
+	j.Unlock()
+
+	if err := j.Save(); err != nil {
+		j.Lock()
+		j.changed = true
+		j.Unlock()
+		return err
+	}
+	return nil
+}
+
+// saveCookies saves MainLoop.Cookies if cookies were set since they were last saved, at most once per cookieSaveMin
+// unless force is set. saveCookies is a no-op if MainLoop.Cookies is nil.
+func (m *MainLoop) saveCookies(force bool) {
+	if m.Cookies == nil {
+		return
+	}
+	min := cookieSaveMin
+	if force {
+		min = 0
+	}
+	if err := m.Cookies.saveChanged(m.now(), min); err != nil {
+		// The cookies are still used, they just won't survive a restart.
+		m.Lock()
+		m.message = "Unable to save cookies: " + err.Error()
+		m.Unlock()
+	}
+}
+
+// sessions keeps track of when the session was bootstrapped for each storefront.
+// The zero value is ready for use.
+type sessions struct {
+	sync.Mutex                      // Protects the field(s) below. Held while bootstrapping.
+	started    map[string]time.Time // Key is storefront host, value is time of the most recent bootstrap.
+}
+
+// bootstrap visits the storefront page given by referer, so that the storefront can set the cookies the API expects.
+// The session is only bootstrapped once per storefront host, unless refresh is set, in which case it is bootstrapped
+// again if it wasn't within sessionRefreshMin. The storefront is visited as the browser described by hp, which must be
+// the same as for the API requests that use the session. bootstrap is a no-op if MainLoop.Cookies is nil.
+func (m *MainLoop) bootstrap(referer string, hp HeaderProfile, refresh bool) error {
+	if m.Cookies == nil {
+		return nil
+	}
+	page := strings.Split(referer, "#")[0]
+	u, err := url.Parse(page)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid storefront URL %q", referer)
+	}
+
+	m.sessions.Lock()
+	defer m.sessions.Unlock()
+	if m.sessions.started == nil {
+		m.sessions.started = make(map[string]time.Time)
+	}
+	started, ok := m.sessions.started[u.Host]
+	if ok && (!refresh || m.now().Sub(started) < sessionRefreshMin) {
+		return nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, page, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("user-agent", hp.UserAgent)
+	req.Header.Set("sec-fetch-site", "none")
+	req.Header.Set("sec-fetch-mode", "navigate")
+	req.Header.Set("sec-fetch-dest", "document")
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("storefront responded with status code %d", resp.StatusCode)
+	}
+
+	m.sessions.started[u.Host] = m.now()
+	m.saveCookies(true)
+	return nil
+}
@@ -0,0 +1,190 @@
+package vuitton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession(t *testing.T) {
+	// The storefront hands out session cookies, and the API only accepts the most recent one.
+	var pageViews, apiCalls int
+	token := "v1"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eng-nl/products/", func(w http.ResponseWriter, r *http.Request) {
+		pageViews++
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: token, Domain: "louisvuitton.com", Path: "/", Expires: time.Now().Add(time.Hour)})
+		_, _ = fmt.Fprint(w, "<html></html>")
+	})
+	mux.HandleFunc("/api/eng-nl/catalog/availability/nvprod3130266v", func(w http.ResponseWriter, r *http.Request) {
+		apiCalls++
+		if c, err := r.Cookie("session"); err != nil || c.Value != token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"skuAvailability": [{"skuId": "1A9JN8", "exists": true, "inStock": true}]}`)
+	})
+	m := newTestLoop(t, mux)
+	cookieFile := filepath.Join(t.TempDir(), "cookies.json")
+	jar, err := NewCookieJar(cookieFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Cookies = jar
+	m.Client.Jar = jar
+
+	p := product{URL: "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8"}
+	check := func(expectedViews, expectedCalls int) {
+		t.Helper()
+		inStock, err := m.availability(p)
+		if err != nil || !inStock {
+			t.Fatalf("expected product in stock, got %v and %v", inStock, err)
+		}
+		if pageViews != expectedViews || apiCalls != expectedCalls {
+			t.Errorf("expected %d page views and %d API calls, got %d and %d", expectedViews, expectedCalls, pageViews, apiCalls)
+		}
+	}
+
+	// The first request bootstraps the session, later requests reuse it.
+	check(1, 1)
+	check(1, 2)
+
+	// Once the session is rejected, it's refreshed and the request is retried.
+	token = "v2"
+	m.sessions.started["en.louisvuitton.com"] = time.Now().Add(-time.Hour)
+	check(2, 4)
+
+	// A session that was just refreshed isn't refreshed again.
+	token = "v3"
+	if _, err := m.availability(p); err == nil {
+		t.Error("expected error for rejected session")
+	}
+	if pageViews != 2 {
+		t.Errorf("expected no additional page views, got %d", pageViews)
+	}
+
+	// Cookies survive a restart.
+	jar, err = NewCookieJar(cookieFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := jar.Cookies(&url.URL{Scheme: "https", Host: "api.louisvuitton.com", Path: "/api/eng-nl/"})
+	if fmt.Sprint(cookies) != "[session=v2]" {
+		t.Errorf("expected [session=v2], got %v", cookies)
+	}
+}
+
+func TestSessionHeaderProfile(t *testing.T) {
+	// Each request records the user agent of the page view and API call it took.
+	var agents []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eng-nl/products/", func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, "page:"+r.UserAgent())
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: r.UserAgent(), Domain: "louisvuitton.com", Path: "/"})
+	})
+	mux.HandleFunc("/api/eng-nl/catalog/availability/nvprod3130266v", func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, "api:"+r.UserAgent())
+		if c, err := r.Cookie("session"); err != nil || c.Value != r.UserAgent() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"skuAvailability": [{"skuId": "1A9JN8", "exists": true, "inStock": true}]}`)
+	})
+	m := newTestLoop(t, mux)
+	jar, err := NewCookieJar(filepath.Join(t.TempDir(), "cookies.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Cookies = jar
+	m.Client.Jar = jar
+	m.HeaderProfiles = []HeaderProfile{{Name: "a", UserAgent: "A"}, {Name: "b", UserAgent: "B"}}
+
+	// The page view and the API call share a profile, and the rotation moves on once per request. The session started
+	// by A is rejected for B, so it's refreshed as B, which becomes possible after sessionRefreshMin.
+	p := product{URL: "https://en.louisvuitton.com/eng-nl/products/charlie-trainers-nvprod3130266v#1A9JN8"}
+	if _, err := m.availability(p); err != nil {
+		t.Fatal(err)
+	}
+	m.sessions.started["en.louisvuitton.com"] = time.Now().Add(-time.Hour)
+	if _, err := m.availability(p); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(agents) != "[page:A api:A api:B page:B api:B]" {
+		t.Errorf("expected [page:A api:A api:B page:B api:B], got %v", agents)
+	}
+}
+
+func TestCookieJarSaveChanged(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cookies.json")
+	jar, err := NewCookieJar(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := &url.URL{Scheme: "https", Host: "api.louisvuitton.com", Path: "/"}
+	start := time.Now()
+	tests := []struct {
+		name    string
+		value   string // Value of the cookie set before saving, empty sets nothing.
+		elapsed time.Duration
+		saved   string
+	}{
+		{"unchanged", "", 0, "[]"},
+		{"changed", "v1", 0, "[session=v1]"},
+		{"debounced", "v2", 10 * time.Second, "[session=v1]"},
+		{"due", "", cookieSaveMin, "[session=v2]"},
+	}
+	for _, tt := range tests {
+		if tt.value != "" {
+			jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: tt.value}})
+		}
+		if err := jar.saveChanged(start.Add(tt.elapsed), cookieSaveMin); err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		saved, err := NewCookieJar(filename)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if got := fmt.Sprint(saved.Cookies(u)); got != tt.saved {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.saved, got)
+		}
+	}
+}
+
+func TestCookieJarClock(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "cookies.json")
+	jar, err := NewCookieJar(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := newFakeClock()
+	jar.Clock = clock
+	expires := clock.Now().Add(time.Hour)
+	jar.SetCookies(&url.URL{Scheme: "https", Host: "api.louisvuitton.com", Path: "/"}, []*http.Cookie{{Name: "session", Value: "v1", Expires: expires}})
+
+	// Expiry is judged by the jar's clock, not the system clock, which is well past the cookie's expiry.
+	tests := []struct {
+		elapsed time.Duration
+		saved   bool
+	}{
+		{0, true},
+		{time.Hour, false},
+	}
+	for _, tt := range tests {
+		clock.Advance(tt.elapsed)
+		if err := jar.Save(); err != nil {
+			t.Fatal(err)
+		}
+		bytes, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved := strings.Contains(string(bytes), `"v1"`); saved != tt.saved {
+			t.Errorf("after %s: expected saved %t, got:\n%s", tt.elapsed, tt.saved, bytes)
+		}
+	}
+}
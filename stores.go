@@ -0,0 +1,167 @@
+package vuitton
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// lvStoreAvailabilityURL is the path template of the endpoint that provides in-store (click and collect) availability
+// on Louis Vuitton's API. The stores are a comma-separated list of store ID's.
+const lvStoreAvailabilityURL = "/api/{locale}/catalog/availability/{id}/stores?storeIds={stores}"
+
+// lvStoresURL is the path template of the endpoint that lists the stores in a country on Louis Vuitton's API.
+const lvStoresURL = "/api/{locale}/stores"
+
+// store describes a boutique, along with the availability of a product's SKU's in that boutique, if requested.
+type store struct {
+	ID              string  `json:"storeId"`
+	Name            string  `json:"name"`
+	City            string  `json:"city"`
+	SKUAvailability []avail `json:"skuAvailability"`
+}
+
+// storesResponse describes the part of the store (availability) responses that we wish to process.
+type storesResponse struct {
+	Stores []store `json:"stores"`
+}
+
+// storeStock is the availability of a product in a single store.
+type storeStock struct {
+	id      string
+	name    string
+	inStock bool
+}
+
+// storeAvailability checks availability of the given product in each of MainLoop.Stores, in the order they are listed.
+// Stores that aren't included in the response are reported as out of stock. storeAvailability returns an empty list
+// if no stores are configured.
+func (m *MainLoop) storeAvailability(p product) ([]storeStock, error) {
+	if len(m.Stores) == 0 {
+		return []storeStock{}, nil
+	}
+	pID := p.productID()
+	if pID == "" {
+		return nil, errors.New("invalid URL or no product ID")
+	}
+	bytes, err := m.fetchStores(p, pID)
+	if err != nil {
+		return nil, err
+	}
+	var resp storesResponse
+	if err := json.Unmarshal(bytes, &resp); err != nil {
+		return nil, decodeError(bytes, err)
+	}
+
+	byID := make(map[string]store, len(resp.Stores))
+	for _, s := range resp.Stores {
+		byID[s.ID] = s
+	}
+	stock := make([]storeStock, 0, len(m.Stores))
+	for _, id := range m.Stores {
+		s, ok := byID[id]
+		name := s.Name
+		if name == "" {
+			name = id
+		}
+		stock = append(stock, storeStock{id: id, name: name, inStock: ok && selectSKU(s.SKUAvailability, p.SKU())})
+	}
+	return stock, nil
+}
+
+// fetchStores returns the in-store availability response body for the given product ID. Like availability responses,
+// it is cached for cacheTTL, so products sharing a product ID only cost one request.
+func (m *MainLoop) fetchStores(p product, pID string) ([]byte, error) {
+	key := cacheKey(m.country(p).Code(), pID)
+	ttl := m.cacheTTL(p)
+	if ttl > 0 {
+		release := m.storeCache.acquire(key)
+		defer release()
+	}
+	sent := m.now()
+	if cached, fresh, _ := m.storeCache.get(key, ttl, sent); ttl > 0 && fresh {
+		return cached.body, nil
+	}
+	ids := url.QueryEscape(strings.Join(m.Stores, ","))
+	bytes, err := m.getBody(p.URL, m.country(p).Code(), m.apiURL(endpointStoreAvailability, "{locale}", m.country(p).Code(), "{id}", pID, "{stores}", ids))
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		m.storeCache.put(key, cachedResponse{body: bytes, fetchedAt: sent})
+	}
+	return bytes, nil
+}
+
+// storeArrivals returns the stores in cur that have the product in stock, but didn't according to prev.
+func storeArrivals(prev, cur []storeStock) []storeStock {
+	had := make(map[string]bool, len(prev))
+	for _, s := range prev {
+		had[s.id] = s.inStock
+	}
+	arrivals := make([]storeStock, 0)
+	for _, s := range cur {
+		if s.inStock && !had[s.id] {
+			arrivals = append(arrivals, s)
+		}
+	}
+	return arrivals
+}
+
+// formatStores returns the names of the stores that have the product in stock, or "None".
+func formatStores(stock []storeStock) string {
+	names := make([]string, 0, len(stock))
+	for _, s := range stock {
+		if s.inStock {
+			names = append(names, s.name)
+		}
+	}
+	if len(names) == 0 {
+		return "None"
+	}
+	return strings.Join(names, ", ")
+}
+
+// inStoreMessage returns the notification message for a product that came in stock in a store.
+func inStoreMessage(key string, md metadata, s storeStock) string {
+	if md.Name == "" {
+		return fmt.Sprintf("Product %q is in stock at %s!", key, s.name)
+	}
+	msg := fmt.Sprintf("%s is in stock at %s!", md.String(), s.name)
+	if p := md.formatPrice(); p != "" {
+		msg += " Price: " + p
+	}
+	return msg
+}
+
+// PrintStores writes a table of the stores in MainLoop.Country to w, so that their ID's can be used with
+// MainLoop.Stores.
+func (m *MainLoop) PrintStores(w io.Writer) error {
+	code := m.Country.Code()
+	referer := fmt.Sprintf("https://%s/%s/stores", localeHosts[code], code)
+	bytes, err := m.getBody(referer, code, m.apiURL(endpointStores, "{locale}", code))
+	if err != nil {
+		return err
+	}
+	var resp storesResponse
+	if err := json.Unmarshal(bytes, &resp); err != nil {
+		return decodeError(bytes, err)
+	}
+	if len(resp.Stores) == 0 {
+		_, _ = fmt.Fprintf(w, "No stores found for %s\n", m.Country)
+		return nil
+	}
+
+	t := tablewriter.NewWriter(w)
+	t.SetHeader([]string{"Store", "Name", "City"})
+	for _, s := range resp.Stores {
+		t.Append([]string{s.ID, s.Name, s.City})
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,111 @@
+package vuitton
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreAvailability(t *testing.T) {
+	var storeIDs string
+	requests := 0
+	m := newTestLoop(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/eng-nl/catalog/availability/nvprod2990025v/stores" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		storeIDs = r.URL.Query().Get("storeIds")
+		bytes, _ := ioutil.ReadFile("testdata/stores_nvprod2990025v.json")
+		_, _ = w.Write(bytes)
+	}))
+	m.Stores = []string{"A47", "A29", "B01"}
+	m.CacheTTL = time.Minute
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-nvprod2990025v#M40995", "[{A47 Louis Vuitton Aarhus false} {A29 Louis Vuitton Copenhagen true} {B01 B01 false}]"},
+		{"https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-nvprod2990025v#M41177", "[{A47 Louis Vuitton Aarhus true} {A29 Louis Vuitton Copenhagen false} {B01 B01 false}]"},
+		{"https://en.louisvuitton.com/eng-nl/products/neverfull-mm-monogram-nvprod2990025v#M00000", "[{A47 Louis Vuitton Aarhus false} {A29 Louis Vuitton Copenhagen false} {B01 B01 false}]"},
+	}
+	for _, test := range tests {
+		stock, err := m.storeAvailability(product{URL: test.url})
+		if err != nil {
+			t.Errorf("unexpected error for %s: %s", test.url, err)
+			continue
+		}
+		if fmt.Sprint(stock) != test.expected {
+			t.Errorf("expected %s for %s, got %v", test.expected, test.url, stock)
+		}
+	}
+	if storeIDs != "A47,A29,B01" {
+		t.Errorf("expected store ID's %q, got %q", "A47,A29,B01", storeIDs)
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request for SKU's sharing a product ID, got %d", requests)
+	}
+
+	m.Stores = nil
+	if stock, err := m.storeAvailability(product{URL: tests[0].url}); err != nil || len(stock) != 0 {
+		t.Errorf("expected no stores and no error without stores, got %v and %v", stock, err)
+	}
+}
+
+func TestStoreArrivals(t *testing.T) {
+	tests := []struct {
+		prev     []storeStock
+		cur      []storeStock
+		expected string
+	}{
+		{nil, []storeStock{{id: "A29", inStock: true}, {id: "A47"}}, "[{A29  true}]"},
+		{[]storeStock{{id: "A29", inStock: true}}, []storeStock{{id: "A29", inStock: true}}, "[]"},
+		{[]storeStock{{id: "A29"}, {id: "A47", inStock: true}}, []storeStock{{id: "A29", inStock: true}, {id: "A47"}}, "[{A29  true}]"},
+		{[]storeStock{{id: "A29", inStock: true}}, []storeStock{{id: "A29"}}, "[]"},
+	}
+	for _, test := range tests {
+		if actual := fmt.Sprint(storeArrivals(test.prev, test.cur)); actual != test.expected {
+			t.Errorf("expected %s for %v -> %v, got %s", test.expected, test.prev, test.cur, actual)
+		}
+	}
+}
+
+func TestFormatStores(t *testing.T) {
+	tests := []struct {
+		stock    []storeStock
+		expected string
+	}{
+		{nil, "None"},
+		{[]storeStock{{name: "Copenhagen"}}, "None"},
+		{[]storeStock{{name: "Copenhagen", inStock: true}, {name: "Aarhus"}, {name: "Paris", inStock: true}}, "Copenhagen, Paris"},
+	}
+	for _, test := range tests {
+		if actual := formatStores(test.stock); actual != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, actual)
+		}
+	}
+}
+
+func TestPrintStores(t *testing.T) {
+	m := newTestLoop(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/eng-nl/stores" {
+			http.NotFound(w, r)
+			return
+		}
+		bytes, _ := ioutil.ReadFile("testdata/stores_dk.json")
+		_, _ = w.Write(bytes)
+	}))
+	b := strings.Builder{}
+	if err := m.PrintStores(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []string{"A29", "Louis Vuitton Copenhagen", "A47", "Aarhus"} {
+		if !strings.Contains(b.String(), e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, b.String())
+		}
+	}
+}
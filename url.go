@@ -0,0 +1,184 @@
+package vuitton
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// lvDomains are the registered domains of Louis Vuitton's storefronts. The host of a product URL must be one of these,
+// or a subdomain of one of these.
+var lvDomains = []string{
+	"louisvuitton.com",
+	"louisvuitton.cn",
+	"louisvuitton.co.jp",
+	"louisvuitton.co.kr",
+}
+
+// localeHosts maps API locales, as found in countryMap, to the host of the storefront for that region.
+var localeHosts = map[string]string{
+	"ara-ae": "ae.louisvuitton.com",
+	"deu-de": "de.louisvuitton.com",
+	"eng-ae": "ae.louisvuitton.com",
+	"eng-au": "au.louisvuitton.com",
+	"eng-ca": "ca.louisvuitton.com",
+	"eng-gb": "uk.louisvuitton.com",
+	"eng-hk": "hk.louisvuitton.com",
+	"eng-nl": "en.louisvuitton.com",
+	"eng-sg": "sg.louisvuitton.com",
+	"eng-us": "us.louisvuitton.com",
+	"esp-es": "es.louisvuitton.com",
+	"esp-mx": "mx.louisvuitton.com",
+	"fra-fr": "fr.louisvuitton.com",
+	"ita-it": "it.louisvuitton.com",
+	"jpn-jp": "jp.louisvuitton.com",
+	"kor-kr": "kr.louisvuitton.com",
+	"por-br": "br.louisvuitton.com",
+	"rus-ru": "ru.louisvuitton.com",
+	"tha-th": "th.louisvuitton.com",
+	"zhs-cn": "www.louisvuitton.cn",
+	"zht-tw": "tw.louisvuitton.com",
+}
+
+// bareIDRegExp matches a bare product ID, eg. "nvprod3130266v".
+var bareIDRegExp = regexp.MustCompile(`^nvprod[0-9a-z]+$`)
+
+// localeRegExp matches the locale segment of a product URL's path, eg. "eng-nl".
+var localeRegExp = regexp.MustCompile(`^[a-z]{3}-[a-z]{2}$`)
+
+// Reasons for rejecting a product URL. They are wrapped in a urlError.
+var (
+	errMalformedURL = errors.New("not a URL")
+	errNotHTTPS     = errors.New("URL must start with https://")
+	errUnknownHost  = errors.New("host is not a Louis Vuitton domain")
+	errNotProduct   = errors.New("not a product page, the path must contain /products/")
+)
+
+// urlError describes why a product URL was rejected. Use errors.Is to check the reason.
+type urlError struct {
+	url string
+	err error
+}
+
+func (e *urlError) Error() string {
+	return fmt.Sprintf("invalid product URL %q: %s", e.url, e.err.Error())
+}
+
+func (e *urlError) Unwrap() error {
+	return e.err
+}
+
+// productURL is a parsed product URL.
+type productURL struct {
+	scheme   string
+	host     string
+	path     string
+	query    url.Values
+	locale   string // API locale found in the path, eg. "eng-nl". Empty if the path has no locale.
+	slug     string // Path segment following "products", eg. "charlie-trainers-nvprod3130266v". May be empty.
+	fragment string // Everything after the first "#", not unescaped.
+}
+
+// parseProductURL parses and validates a product URL. The URL must use https, point to one of the domains in
+// lvDomains, and have a path containing a "products" segment, or a query that identifies a product.
+// parseProductURL returns a *urlError if the URL is invalid.
+func parseProductURL(raw string) (productURL, error) {
+	// The fragment holds the SKU's, which we parse ourselves, as it may contain whitespace that url.Parse rejects.
+	base, fragment := raw, ""
+	if i := strings.IndexByte(raw, '#'); i >= 0 {
+		base, fragment = raw[:i], raw[i+1:]
+	}
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		return productURL{}, &urlError{url: raw, err: errMalformedURL}
+	}
+	if u.Scheme != "https" {
+		return productURL{}, &urlError{url: raw, err: errNotHTTPS}
+	}
+	if !knownHost(u.Hostname()) {
+		return productURL{}, &urlError{url: raw, err: errUnknownHost}
+	}
+
+	pu := productURL{scheme: u.Scheme, host: u.Host, path: u.Path, query: u.Query(), fragment: fragment}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	isProduct := false
+	for i, seg := range segments {
+		// The locale is usually the first segment, but mobile site URL's may have a prefix.
+		if pu.locale == "" && localeRegExp.MatchString(seg) {
+			pu.locale = seg
+		}
+		if seg == "products" {
+			isProduct = true
+			if i+1 < len(segments) {
+				pu.slug = segments[i+1]
+			}
+			break
+		}
+	}
+	if !isProduct && !productQuery(pu.query) {
+		return productURL{}, &urlError{url: raw, err: errNotProduct}
+	}
+	return pu, nil
+}
+
+// productQuery returns true if the query identifies a product, by product ID or by model code.
+func productQuery(query url.Values) bool {
+	for _, vs := range query {
+		for _, v := range vs {
+			if productRegExp.MatchString(strings.ToLower(v)) {
+				return true
+			}
+		}
+	}
+	for _, key := range modelQueryKeys {
+		if modelRegExp.MatchString(strings.ToUpper(query.Get(key))) {
+			return true
+		}
+	}
+	return false
+}
+
+// bareURL converts a bare product ID or model code, optionally followed by "#" and SKU's, into a product URL on the
+// storefront of the given country, eg. "nvprod3130266v#1A9JN8" becomes
+// "https://en.louisvuitton.com/eng-nl/products/nvprod3130266v#1A9JN8" for Denmark. The URL is used as referer for
+// API requests, and its locale determines the country that availability is checked in.
+// bareURL returns false if s is not a bare product ID or model code.
+func bareURL(s string, c Country) (string, bool) {
+	id, fragment := s, ""
+	if i := strings.IndexByte(s, '#'); i >= 0 {
+		id, fragment = s[:i], s[i:]
+	}
+	switch {
+	case bareIDRegExp.MatchString(strings.ToLower(id)):
+		id = strings.ToLower(id)
+	case modelRegExp.MatchString(strings.ToUpper(id)) && !numberRegExp.MatchString(id):
+		id = strings.ToUpper(id)
+	default:
+		return "", false
+	}
+	host, ok := localeHosts[c.Code()]
+	if !ok {
+		host = localeHosts["eng-nl"]
+	}
+	return fmt.Sprintf("https://%s/%s/products/%s%s", host, c.Code(), id, fragment), true
+}
+
+// isShortLink returns true if raw points to one of lvDomains, but not to a product page. Such URL's may be short links
+// that redirect to a product page, see MainLoop.follow.
+func isShortLink(raw string) bool {
+	_, err := parseProductURL(raw)
+	return errors.Is(err, errNotProduct)
+}
+
+// knownHost returns true if host is one of lvDomains, or a subdomain of one of them.
+func knownHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, d := range lvDomains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
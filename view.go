@@ -1,6 +1,7 @@
 package vuitton
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
@@ -31,11 +32,15 @@ func (m *MainLoop) updateView() {
 
 	b := strings.Builder{}
 
-	inStock := func(val bool) string {
-		if val {
+	inStock := func(lvl stockLevel) string {
+		switch {
+		case lvl.err != nil:
+			return errorLabel(lvl.err)
+		case lvl.inStock:
 			return "Yes"
+		default:
+			return "No"
 		}
-		return "No"
 	}
 
 	// Render header info.
@@ -43,35 +48,90 @@ func (m *MainLoop) updateView() {
 	h := tablewriter.NewWriter(&b)
 	h.SetBorder(false)
 	h.SetAlignment(tablewriter.ALIGN_LEFT)
-	h.Append([]string{"Region", m.Country.Code()})
+	h.Append([]string{"Default region", m.Country.Code()})
 	h.Append([]string{"Product file", m.PFileName})
 	h.Append([]string{"Products found", strconv.Itoa(len(m.products))})
-	h.Append([]string{"Check interval", m.AvailabilityInterval.String()})
+	h.Append([]string{"Check interval", m.effectiveInterval().String()})
+	if m.RequestBudget > 0 {
+		h.Append([]string{"Request budget", fmt.Sprintf("%d/min", m.RequestBudget)})
+	}
+	if len(m.Watches) > 0 {
+		h.Append([]string{"Watching", fmt.Sprintf("%d categories/searches every %s", len(m.Watches), m.WatchInterval)})
+	}
 	h.Render()
 	b.WriteString("\n")
 
 	// Render stock level table.
 	t := tablewriter.NewWriter(&b)
-	t.SetHeader([]string{"Product", "SKU", "In stock?"})
-	pIDs := make([]string, 0, len(m.products))
-	for pID := range m.products {
-		pIDs = append(pIDs, pID)
+	header := []string{"Product", "Country", "Name", "SKU", "Variant", "Price", "Priority", "Interval", "In stock?"}
+	if len(m.Stores) > 0 {
+		header = append(header, "In store")
+	}
+	t.SetHeader(header)
+	keys := make([]string, 0, len(m.products))
+	for key := range m.products {
+		keys = append(keys, key)
 	}
-	sort.Strings(pIDs)
-	for _, pID := range pIDs {
-		stockLevel := m.products[pID]
+	sort.Strings(keys)
+	var errs []string
+	var diag string // Diagnostics of the first error that needs attention.
+	now := m.now()
+	for _, key := range keys {
+		stockLevel := m.products[key]
+		pID := stockLevel.product.productID()
 		if !stockLevel.product.Valid() {
 			pID = "Invalid product URL!"
 		}
-		t.Append([]string{fmt.Sprintf("%-*s", pIDPadding, pID), stockLevel.product.SKU(), inStock(stockLevel.inStock)})
+		interval := m.interval(stockLevel, now).String()
+		if m.hot(stockLevel, now) {
+			interval += " (hot)"
+		}
+		row := []string{
+			fmt.Sprintf("%-*s", pIDPadding, pID),
+			string(m.country(stockLevel.product)),
+			stockLevel.meta.Name,
+			stockLevel.product.SKU(),
+			stockLevel.meta.variant(),
+			stockLevel.formatPrice(),
+			stockLevel.product.Priority.String(),
+			interval,
+			inStock(stockLevel),
+		}
+		if len(m.Stores) > 0 {
+			row = append(row, formatStores(stockLevel.stores))
+		}
+		t.Append(row)
+		if stockLevel.err != nil {
+			errs = append(errs, fmt.Sprintf("Unable to check availability of %q: %s", key, stockLevel.err.Error()))
+			var ae *apiError
+			if diag == "" && alertKind(stockLevel.err) != nil && errors.As(stockLevel.err, &ae) && ae.diagnostics() != "" {
+				diag = fmt.Sprintf("\nResponse for %q:\n%s\n", key, ae.diagnostics())
+			}
+		}
+		if stockLevel.storeErr != nil {
+			errs = append(errs, fmt.Sprintf("Unable to check store availability of %q: %s", key, stockLevel.storeErr.Error()))
+		}
 	}
 	t.Render()
 
-	// Render message.
+	// Render proxy stats.
+	if m.Proxies != nil {
+		b.WriteString("\n")
+		p := tablewriter.NewWriter(&b)
+		p.SetHeader([]string{"Proxy", "Requests", "Failures", "Status"})
+		p.AppendBulk(m.Proxies.rows(now))
+		p.Render()
+	}
+
+	// Render message and errors.
 	if m.message != "" {
 		b.WriteString("\n")
 		b.WriteString(m.message + "\n")
 	}
+	for _, e := range errs {
+		b.WriteString(e + "\n")
+	}
+	b.WriteString(diag)
 
 	m.ViewPort.Clear()
 	m.ViewPort.Update(b.String())
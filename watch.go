@@ -0,0 +1,130 @@
+package vuitton
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// lvCategoryURL is the path template of the endpoint that lists the products in a category on Louis Vuitton's API.
+// The response has the same format as the search response.
+const lvCategoryURL = "/api/{locale}/catalog/category/{category}"
+
+// watchState keeps track of the products listed by a watched category or search.
+type watchState struct {
+	seen   map[string]bool // Key is product ID.
+	polled bool            // False until the first successful poll, which only records the products already listed.
+}
+
+// parseCategoryURL extracts the API locale and category ID from a category URL, such as
+// https://en.louisvuitton.com/eng-nl/women/handbags/_/N-tfr7qdp, where the category ID is "N-tfr7qdp".
+// The locale is empty if the URL has none.
+func parseCategoryURL(raw string) (locale, id string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "", "", &urlError{url: raw, err: errMalformedURL}
+	}
+	if u.Scheme != "https" {
+		return "", "", &urlError{url: raw, err: errNotHTTPS}
+	}
+	if !knownHost(u.Hostname()) {
+		return "", "", &urlError{url: raw, err: errUnknownHost}
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if localeRegExp.MatchString(segments[0]) {
+		locale = segments[0]
+	}
+	id = segments[len(segments)-1]
+	if id == "" || id == locale {
+		return "", "", &urlError{url: raw, err: errors.New("no category in URL")}
+	}
+	return locale, id, nil
+}
+
+// listing returns the products currently listed by the watch, which is either a category URL, or a search query, and
+// the country they are listed in. Categories are listed in the country given by the URL's locale, searches in
+// MainLoop.Country.
+func (m *MainLoop) listing(w string) ([]searchHit, Country, error) {
+	if !strings.HasPrefix(w, "https://") && !strings.HasPrefix(w, "http://") {
+		hits, err := m.search(w)
+		return hits, m.Country, err
+	}
+	locale, id, err := parseCategoryURL(w)
+	if err != nil {
+		return nil, "", err
+	}
+	c, ok := localeCountry(locale)
+	if !ok {
+		c = m.Country
+	}
+	bytes, err := m.getBody(w, c.Code(), m.apiURL(endpointCategory, "{locale}", c.Code(), "{category}", url.PathEscape(id)))
+	if err != nil {
+		return nil, "", err
+	}
+	hits, err := parseSearch(bytes)
+	return hits, c, err
+}
+
+// newHits returns the hits whose product ID's haven't been seen before, and marks them as seen.
+func (s *watchState) newHits(hits []searchHit) []searchHit {
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	fresh := make([]searchHit, 0)
+	for _, h := range hits {
+		if !s.seen[h.ProductID] {
+			s.seen[h.ProductID] = true
+			fresh = append(fresh, h)
+		}
+	}
+	return fresh
+}
+
+// pollWatches polls each watched category or search, and notifies about products that weren't listed before. Products
+// listed on the first poll of a watch are considered known. If AutoAdd is set, new products are appended to the
+// P-file, and will be monitored from the next reload.
+// pollWatches must be called without the lock held.
+func (m *MainLoop) pollWatches() {
+	for _, w := range m.Watches {
+		hits, c, err := m.listing(w)
+
+		m.Lock()
+		if m.watches == nil {
+			m.watches = make(map[string]*watchState)
+		}
+		state, ok := m.watches[w]
+		if !ok {
+			state = &watchState{}
+			m.watches[w] = state
+		}
+		if err != nil {
+			m.message = fmt.Sprintf("Unable to check %q for new products: %s", w, err.Error())
+			m.Unlock()
+			continue
+		}
+		fresh := state.newHits(hits)
+		first := !state.polled
+		state.polled = true
+		m.Unlock()
+
+		if first || len(fresh) == 0 {
+			continue
+		}
+		lines := make([]string, 0, len(fresh))
+		for _, h := range fresh {
+			if m.Notification != nil {
+				m.Notification("Vuitton Monitor", fmt.Sprintf("New product in %q: %s (%s)", w, h.Name, h.ProductID))
+			}
+			lines = append(lines, h.pageURL(c))
+		}
+		if !m.AutoAdd {
+			continue
+		}
+		if err := m.appendPFile(lines); err != nil {
+			m.Lock()
+			m.message = fmt.Sprintf("Unable to add new products to %s: %s", m.PFileName, err.Error())
+			m.Unlock()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package vuitton
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCategoryURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		locale string
+		id     string
+		err    error
+	}{
+		{"https://en.louisvuitton.com/eng-nl/women/handbags/_/N-tfr7qdp", "eng-nl", "N-tfr7qdp", nil},
+		{"https://us.louisvuitton.com/eng-us/new/for-women/_/N-t1rrahxp/", "eng-us", "N-t1rrahxp", nil},
+		{"https://www.louisvuitton.cn/women/handbags", "", "handbags", nil},
+		{"http://en.louisvuitton.com/eng-nl/women/handbags/_/N-tfr7qdp", "", "", errNotHTTPS},
+		{"https://example.com/eng-nl/women/handbags/_/N-tfr7qdp", "", "", errUnknownHost},
+		{"https://en.louisvuitton.com/eng-nl/", "", "", nil},
+		{"not a url", "", "", errMalformedURL},
+	}
+	for _, test := range tests {
+		locale, id, err := parseCategoryURL(test.url)
+		if locale != test.locale || id != test.id {
+			t.Errorf("expected %q and %q for %s, got %q and %q", test.locale, test.id, test.url, locale, id)
+		}
+		if test.id == "" && err == nil {
+			t.Errorf("expected error for %s", test.url)
+		}
+		if test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("expected %q for %s, got %v", test.err, test.url, err)
+		}
+	}
+}
+
+func TestPollWatches(t *testing.T) {
+	polls := 0
+	m := newTestLoop(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/eng-us/catalog/category/N-tfr7qdp" {
+			http.NotFound(w, r)
+			return
+		}
+		polls++
+		switch polls {
+		case 1:
+			_, _ = fmt.Fprint(w, `{"hits": [{"identifier": "nvprod2990025v", "name": "Neverfull MM"}]}`)
+			return
+		case 2:
+			_, _ = fmt.Fprint(w, `{"hits": [{"identifier": "nvprod2990025v"}, {"identifier": "nvprod2210007v", "name": "Neverfull GM"}]}`)
+			return
+		}
+		bytes, _ := ioutil.ReadFile("testdata/search_neverfull.json")
+		_, _ = w.Write(bytes)
+	}))
+	var notifications []string
+	m.Notification = func(title, msg string) { notifications = append(notifications, msg) }
+	m.Watches = []string{"https://us.louisvuitton.com/eng-us/women/handbags/_/N-tfr7qdp"}
+	m.PFileName = filepath.Join(t.TempDir(), "products.txt")
+
+	// The first poll only records the products already listed.
+	m.pollWatches()
+	if len(notifications) != 0 {
+		t.Errorf("expected no notifications after first poll, got %q", notifications)
+	}
+
+	m.pollWatches()
+	expected := `[New product in "https://us.louisvuitton.com/eng-us/women/handbags/_/N-tfr7qdp": Neverfull GM (nvprod2210007v)]`
+	if fmt.Sprint(notifications) != expected {
+		t.Errorf("expected %s, got %s", expected, notifications)
+	}
+	if _, err := ioutil.ReadFile(m.PFileName); err == nil {
+		t.Error("expected no products to be added without AutoAdd")
+	}
+
+	// Products are only reported once, and new ones are added to the P-file.
+	notifications = nil
+	m.AutoAdd = true
+	m.pollWatches()
+	expected = `[New product in "https://us.louisvuitton.com/eng-us/women/handbags/_/N-tfr7qdp": Neverfull Pouch (nvprod3460161v)]`
+	if fmt.Sprint(notifications) != expected {
+		t.Errorf("expected %s, got %s", expected, notifications)
+	}
+	bytes, err := ioutil.ReadFile(m.PFileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bytes) != "https://us.louisvuitton.com/eng-us/products/nvprod3460161v\n" {
+		t.Errorf("expected new product in product file, got:\n%s", bytes)
+	}
+	if m.message != "" {
+		t.Errorf("expected no message, got %q", m.message)
+	}
+}